@@ -1,46 +1,465 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
-	"github.com/HASHIMOTO-Takafumi/tfstate-diff/internal"
+	"github.com/HASHIMOTO-Takafumi/tfstate-diff/tfstatediff"
 )
 
+const (
+	exitNoDiff = 0
+	exitDiff   = 1
+	exitError  = 2
+)
+
+type options struct {
+	configPath    string
+	schema        string
+	rightSchema   string
+	mergeSchemas  bool
+	left          string
+	right         string
+	base          string
+	showSensitive bool
+	baseline      string
+	writeBaseline string
+	verbose       bool
+	output        string
+	template      string
+	keepGoing     bool
+	explain       bool
+	reportUnused  bool
+}
+
 func main() {
-	var verbose = flag.Bool("v", false, "be verbose")
-	var json = flag.Bool("j", false, "output json")
-	var c = flag.String("c", "", "YAML configuration file")
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(validateConfig(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "matrix" {
+		os.Exit(matrix(os.Args[2:]))
+	}
+
+	var o options
+	var printJson bool
+	var failOn string
+
+	flag.BoolVar(&o.verbose, "v", false, "be verbose")
+	flag.BoolVar(&printJson, "j", false, "output json (same as -o json)")
+	flag.StringVar(&o.output, "o", "text", "output format: text, json or markdown")
+	flag.StringVar(&o.template, "template", "", "Go text/template file to render the result (overrides -o)")
+	flag.StringVar(&o.configPath, "c", "", "YAML configuration file")
+	flag.StringVar(&o.rightSchema, "rs", "", "providers schema for the right side (default: same as the left)")
+	flag.BoolVar(&o.mergeSchemas, "merge-schemas", false, "use the union of left and right schemas for both sides")
+	flag.BoolVar(&o.keepGoing, "keep-going", false, "record errors of each resource and continue")
+	flag.BoolVar(&o.explain, "explain", false, "record suppressed diffs with the rules which suppressed them")
+	flag.BoolVar(&o.reportUnused, "report-unused", false, "report configured rules which never matched to stderr")
+	flag.StringVar(&o.base, "base", "", "tfstate or plan of a common base to classify changes of left and right (three-way)")
+	flag.StringVar(&o.baseline, "baseline", "", "JSON file of accepted diffs to hide")
+	flag.StringVar(&o.writeBaseline, "write-baseline", "", "write the diffs of this comparison to the file as accepted")
+	flag.BoolVar(&o.showSensitive, "show-sensitive", false, "print sensitive values instead of whether they changed")
+	flag.StringVar(&failOn, "fail-on", "any", "comma separated kinds of differences to exit with 1: diffs, left-only, right-only, any, none or unused-rules")
 
 	flag.Usage = usage
 	flag.Parse()
 
 	if flag.NArg() < 3 {
 		usage()
-		return
+		os.Exit(exitError)
+	}
+
+	if printJson {
+		o.output = "json"
+	}
+
+	o.schema = flag.Arg(0)
+	o.left = flag.Arg(1)
+	o.right = flag.Arg(2)
+
+	kinds, err := parseFailOn(failOn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+
+	o.reportUnused = o.reportUnused || kinds.unusedRules
+
+	if o.base != "" {
+		os.Exit(threeWay(o, kinds))
 	}
 
-	var s = flag.Arg(0)
-	var l = flag.Arg(1)
-	var r = flag.Arg(2)
+	result, unused, err := run(o)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
 
-	comparer, err := internal.New(*c, s)
+	os.Exit(exitCode(result, unused, kinds))
+}
+
+func newComparer(o options) (*tfstatediff.Comparer, error) {
+	config := tfstatediff.Config{}
+	if o.configPath != "" {
+		var err error
+		if config, err = tfstatediff.LoadConfig(o.configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	psL, err := tfstatediff.LoadProvidersSchema(o.schema)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+
+	psR := psL
+	if o.rightSchema != "" {
+		if psR, err = tfstatediff.LoadProvidersSchema(o.rightSchema); err != nil {
+			return nil, err
+		}
 	}
 
-	if *verbose {
+	if o.mergeSchemas {
+		psL = tfstatediff.MergeProvidersSchemas(psL, psR)
+		psR = psL
+	}
+
+	comparer, err := tfstatediff.NewWithSchemas(config, psL, psR)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.verbose {
 		comparer.SetDetailWriter(os.Stdout)
 	}
+	comparer.SetKeepGoing(o.keepGoing)
+	comparer.SetExplain(o.explain)
+	comparer.SetShowSensitive(o.showSensitive)
+
+	if o.baseline != "" {
+		b, err := tfstatediff.LoadBaseline(o.baseline)
+		if err != nil {
+			return nil, err
+		}
+		comparer.SetBaseline(b)
+	}
+
+	return comparer, nil
+}
+
+func run(o options) (*tfstatediff.ComparisonResult, []string, error) {
+	comparer, err := newComparer(o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stateL, err := tfstatediff.LoadState(o.left)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stateR, err := tfstatediff.LoadState(o.right)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := comparer.Compare(stateL, stateR)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := printResult(o, result); err != nil {
+		return nil, nil, err
+	}
+
+	if o.writeBaseline != "" {
+		if err := writeBaseline(o.writeBaseline, result); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return result, reportUnused(o, comparer), nil
+}
+
+func reportUnused(o options, comparer *tfstatediff.Comparer) []string {
+	unused := comparer.UnusedRules()
+	if o.reportUnused && len(unused) > 0 {
+		fmt.Fprintln(os.Stderr, "unused rules:")
+		for _, u := range unused {
+			fmt.Fprintf(os.Stderr, "  %s\n", u)
+		}
+	}
+	return unused
+}
 
-	if err = comparer.Compare(l, r, *json); err != nil {
-		fmt.Println(err)
+func writeBaseline(path string, result *tfstatediff.ComparisonResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+
+	return tfstatediff.NewBaseline(result).Write(f)
+}
+
+type printableResult interface {
+	Print(w io.Writer)
+	PrintMarkdown(w io.Writer)
+}
+
+func printResult(o options, result printableResult) error {
+	if o.template != "" {
+		t, err := tfstatediff.LoadTemplate(o.template)
+		if err != nil {
+			return err
+		}
+		return t.Execute(os.Stdout, result)
+	}
+
+	switch o.output {
+	case "json":
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	case "markdown":
+		result.PrintMarkdown(os.Stdout)
+	case "text":
+		result.Print(os.Stdout)
+	default:
+		return fmt.Errorf("unknown output format: %s", o.output)
+	}
+
+	return nil
+}
+
+type failOnKinds struct {
+	diffs       bool
+	leftOnly    bool
+	rightOnly   bool
+	unusedRules bool
+}
+
+func parseFailOn(s string) (failOnKinds, error) {
+	k := failOnKinds{}
+
+	for _, v := range strings.Split(s, ",") {
+		switch strings.TrimSpace(v) {
+		case "diffs":
+			k.diffs = true
+		case "left-only":
+			k.leftOnly = true
+		case "right-only":
+			k.rightOnly = true
+		case "any":
+			k.diffs, k.leftOnly, k.rightOnly = true, true, true
+		case "unused-rules":
+			k.unusedRules = true
+		case "none", "":
+		default:
+			return k, fmt.Errorf("unknown kind for -fail-on: %s", v)
+		}
+	}
+
+	return k, nil
+}
+
+// exitCode judges by the plan diff if any, since it is what the environments will be
+func exitCode(result *tfstatediff.ComparisonResult, unused []string, k failOnKinds) int {
+	d := result.StateDiff
+	if result.PlanDiff != nil {
+		d = result.PlanDiff
+	}
+
+	if len(result.StateDiff.Errors) > 0 || (result.PlanDiff != nil && len(result.PlanDiff.Errors) > 0) {
+		// the comparison is incomplete
+		return exitError
+	}
+
+	if (k.diffs && (len(d.Diffs) > 0 || len(d.OutputDiffs) > 0)) || (k.leftOnly && len(d.LeftOnly) > 0) || (k.rightOnly && len(d.RightOnly) > 0) || (k.unusedRules && len(unused) > 0) {
+		return exitDiff
+	}
+
+	return exitNoDiff
+}
+
+func validateConfig(args []string) int {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	var s = fs.String("s", "", "providers schema to check resource types and attributes")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s validate-config [-s schema.json] config.yaml\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return exitError
+	}
+	path := fs.Arg(0)
+
+	var ps *tfstatediff.TfProvidersSchema
+	if *s != "" {
+		schema, err := tfstatediff.LoadProvidersSchema(*s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		ps = &schema
+	}
+
+	diagnostics, err := tfstatediff.ValidateConfig(path, ps)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	code := exitNoDiff
+	for _, d := range diagnostics {
+		fmt.Printf("%s:%s\n", path, d)
+		if d.Severity == "error" {
+			code = exitError
+		}
+	}
+
+	return code
+}
+
+// threeWay judges by -fail-on: diffs are changed fields, and left-only and right-only are resources added or removed on the side
+func threeWay(o options, k failOnKinds) int {
+	if o.baseline != "" || o.writeBaseline != "" {
+		fmt.Fprintln(os.Stderr, "-baseline and -write-baseline cannot be used with -base")
+		return exitError
+	}
+
+	comparer, err := newComparer(o)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	states := []*tfstatediff.TfStatePlan{}
+	for _, path := range []string{o.base, o.left, o.right} {
+		state, err := tfstatediff.LoadState(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		states = append(states, state)
+	}
+
+	result, err := comparer.CompareThreeWay(states[0], states[1], states[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if err := printResult(o, result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	unused := reportUnused(o, comparer)
+
+	if len(result.Left.Errors) > 0 || len(result.Right.Errors) > 0 {
+		return exitError
+	}
+
+	l, r := result.Left, result.Right
+	if (k.diffs && len(result.Fields) > 0) || (k.leftOnly && len(l.LeftOnly)+len(l.RightOnly) > 0) || (k.rightOnly && len(r.LeftOnly)+len(r.RightOnly) > 0) || (k.unusedRules && len(unused) > 0) {
+		return exitDiff
+	}
+
+	return exitNoDiff
+}
+
+func matrix(args []string) int {
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	var o options
+	fs.StringVar(&o.configPath, "c", "", "YAML configuration file")
+	fs.BoolVar(&o.verbose, "v", false, "be verbose")
+	fs.StringVar(&o.output, "o", "text", "output format: text, json or markdown")
+	fs.BoolVar(&o.keepGoing, "keep-going", false, "record errors of each resource and continue")
+	fs.BoolVar(&o.showSensitive, "show-sensitive", false, "print sensitive values instead of whether they changed")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s matrix [flags] schema.json label=tfstate.json label=tfstate.json...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 3 {
+		fs.Usage()
+		return exitError
+	}
+
+	o.schema = fs.Arg(0)
+	result, err := runMatrix(o, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	switch o.output {
+	case "json":
+		j, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		fmt.Println(string(j))
+	case "markdown":
+		result.PrintMarkdown(os.Stdout)
+	case "text":
+		result.Print(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown output format: %s\n", o.output)
+		return exitError
+	}
+
+	if len(result.Errors) > 0 {
+		// the comparison is incomplete
+		return exitError
+	}
+
+	for _, r := range result.Resources {
+		if len(r.Environments) < len(result.Labels) || len(r.Fields) > 0 {
+			return exitDiff
+		}
+	}
+
+	return exitNoDiff
+}
+
+func runMatrix(o options, args []string) (*tfstatediff.MatrixResult, error) {
+	comparer, err := newComparer(o)
+	if err != nil {
+		return nil, err
+	}
+
+	states := []tfstatediff.LabeledState{}
+	for _, arg := range args {
+		label, path, ok := strings.Cut(arg, "=")
+		if !ok || label == "" {
+			return nil, fmt.Errorf("expected label=tfstate.json: %s", arg)
+		}
+
+		state, err := tfstatediff.LoadState(path)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, tfstatediff.LabeledState{Label: label, State: state})
+	}
+
+	return comparer.CompareMatrix(states)
 }
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "usage: %s [flags] schema.json left_tfstate.json right_tfstate.json\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s validate-config [-s schema.json] config.yaml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s matrix [flags] schema.json label=tfstate.json label=tfstate.json...\n", os.Args[0])
 	flag.PrintDefaults()
 }
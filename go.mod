@@ -5,7 +5,7 @@ go 1.19
 require (
 	github.com/koron/go-dproxy v1.3.0
 	github.com/wI2L/jsondiff v0.2.0
-	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,79 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type addressMapRule struct {
+	hits    *int
+	source  string
+	pattern *regexp.Regexp
+	replace string
+	left    string
+	right   string
+}
+
+func (r addressMapRule) String() string {
+	if r.pattern != nil {
+		return fmt.Sprintf("%s => %s", r.source, r.replace)
+	}
+	return fmt.Sprintf("%s = %s", r.left, r.right)
+}
+
+type addressMapper struct {
+	rules []addressMapRule
+}
+
+func newAddressMapper(c []ConfigAddressMap) (addressMapper, error) {
+	rules := make([]addressMapRule, len(c))
+
+	for i := range c {
+		if c[i].Pattern != "" {
+			if c[i].Left != "" || c[i].Right != "" {
+				return addressMapper{}, fmt.Errorf("address_map[%d]: pattern cannot be used with left/right", i)
+			}
+			// match the whole address
+			re, err := regexp.Compile("^(?:" + c[i].Pattern + ")$")
+			if err != nil {
+				return addressMapper{}, err
+			}
+			rules[i] = addressMapRule{hits: new(int), source: c[i].Pattern, pattern: re, replace: c[i].Replace}
+		} else {
+			if c[i].Left == "" || c[i].Right == "" {
+				return addressMapper{}, fmt.Errorf("address_map[%d]: either pattern or both left and right are required", i)
+			}
+			rules[i] = addressMapRule{hits: new(int), left: c[i].Left, right: c[i].Right}
+		}
+	}
+
+	return addressMapper{rules: rules}, nil
+}
+
+// mapLeft rewrites a lefthand address into the righthand one by the first matching rule
+func (m addressMapper) mapLeft(address string) (string, *addressMapRule) {
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.pattern != nil {
+			if r.pattern.MatchString(address) {
+				return r.pattern.ReplaceAllString(address, r.replace), r
+			}
+		} else if r.left == address {
+			return r.right, r
+		}
+	}
+
+	return address, nil
+}
+
+// mapResources returns copies of lefthand resources whose addresses are rewritten
+func (m addressMapper) mapResources(rs []TfResource) []TfResource {
+	mapped := make([]TfResource, len(rs))
+
+	for i := range rs {
+		mapped[i] = rs[i]
+		mapped[i].Address, _ = m.mapLeft(rs[i].Address)
+	}
+
+	return mapped
+}
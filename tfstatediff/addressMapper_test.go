@@ -0,0 +1,56 @@
+package tfstatediff
+
+import (
+	"testing"
+)
+
+func TestAddressMapperMapLeft(t *testing.T) {
+	m, err := newAddressMapper([]ConfigAddressMap{
+		{Pattern: `module\.stg_(.+)`, Replace: "module.prod_$1"},
+		{Left: "aws_s3_bucket.stg_logs", Right: "aws_s3_bucket.logs"},
+		// shadowed by the first rule
+		{Left: "module.stg_a.aws_vpc.a", Right: "module.other.aws_vpc.a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		address string
+		want    string
+		rule    int
+	}{
+		{"module.stg_a.aws_vpc.a", "module.prod_a.aws_vpc.a", 0},
+		{"module.stg_a.module.b.aws_subnet.a", "module.prod_a.module.b.aws_subnet.a", 0},
+		{"aws_s3_bucket.stg_logs", "aws_s3_bucket.logs", 1},
+		// patterns match the whole address
+		{"module.x.module.stg_a.aws_vpc.a", "module.x.module.stg_a.aws_vpc.a", -1},
+		{"aws_s3_bucket.stg_logs_2", "aws_s3_bucket.stg_logs_2", -1},
+	}
+
+	for _, tt := range tests {
+		got, rule := m.mapLeft(tt.address)
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.address, got, tt.want)
+		}
+		if tt.rule < 0 {
+			if rule != nil {
+				t.Errorf("%s: mapped by %s", tt.address, rule)
+			}
+		} else if rule != &m.rules[tt.rule] {
+			t.Errorf("%s: mapped by %v, want address_map[%d]", tt.address, rule, tt.rule)
+		}
+	}
+}
+
+func TestNewAddressMapperErrors(t *testing.T) {
+	for _, c := range []ConfigAddressMap{
+		{Pattern: "a", Left: "b"},
+		{Left: "a"},
+		{Pattern: "("},
+	} {
+		if _, err := newAddressMapper([]ConfigAddressMap{c}); err == nil {
+			t.Errorf("%+v: no error", c)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tfstatediff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Baseline is a set of accepted diffs, hidden as long as both values stay the same
+type Baseline struct {
+	Accepted []AcceptedDiff `json:"accepted"`
+}
+
+type AcceptedDiff struct {
+	Address  string `json:"address"`
+	Path     string `json:"path"` // prefixed by the policy or document attribute and the statement if any
+	OldValue any    `json:"old_value"`
+	NewValue any    `json:"new_value"`
+}
+
+type acceptedDiffRule struct {
+	AcceptedDiff
+	hits *int
+}
+
+// NewBaseline accepts all field diffs of the result, of both the state and the plan.
+// Masked sensitive diffs are left out, since baseline files are shared and hashes of secrets can be brute-forced.
+func NewBaseline(cr *ComparisonResult) Baseline {
+	b := Baseline{Accepted: []AcceptedDiff{}}
+	found := map[string]bool{}
+
+	add := func(address string, path string, f FieldDiff) {
+		if f.masked() {
+			return
+		}
+		a := AcceptedDiff{Address: address, Path: path, OldValue: f.OldValue, NewValue: f.NewValue}
+		key := fmt.Sprintf("%s %s %v %v", a.Address, a.Path, a.OldValue, a.NewValue)
+		if !found[key] {
+			found[key] = true
+			b.Accepted = append(b.Accepted, a)
+		}
+	}
+
+	walkFields(cr.StateDiff, add)
+	if cr.PlanDiff != nil {
+		walkFields(cr.PlanDiff, add)
+	}
+
+	return b
+}
+
+func LoadBaseline(path string) (Baseline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Baseline{}, err
+	}
+	defer f.Close()
+
+	var b Baseline
+	if err := json.NewDecoder(f).Decode(&b); err != nil {
+		return b, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return b, nil
+}
+
+func (b Baseline) Write(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(b)
+}
+
+// SetBaseline makes the comparison hide the accepted diffs of b
+func (c *Comparer) SetBaseline(b Baseline) {
+	c.baseline = make([]acceptedDiffRule, len(b.Accepted))
+	for i := range b.Accepted {
+		c.baseline[i] = acceptedDiffRule{AcceptedDiff: b.Accepted[i], hits: new(int)}
+	}
+}
+
+// acceptedBy never accepts masked sensitive diffs, whose values tell only whether they changed
+func (c Comparer) acceptedBy(address string, path string, f FieldDiff) *acceptedDiffRule {
+	if f.masked() {
+		return nil
+	}
+	for i := range c.baseline {
+		a := c.baseline[i]
+		if a.Address == address && a.Path == path && fmt.Sprint(a.OldValue) == fmt.Sprint(f.OldValue) && fmt.Sprint(a.NewValue) == fmt.Sprint(f.NewValue) {
+			return &c.baseline[i]
+		}
+	}
+	return nil
+}
+
+// applyBaseline removes the accepted field diffs and the resource diffs which became empty
+func (c Comparer) applyBaseline(d *StateDiff) {
+	if len(c.baseline) == 0 {
+		return
+	}
+
+	diffs := []ResourceDiff{}
+
+	for _, rd := range d.Diffs {
+		rd.Fields = c.unaccepted(rd.Name, nil, rd.Fields)
+
+		policies := []ResourceDiff{}
+		for _, pd := range rd.Policies {
+			pd.Fields = c.unaccepted(rd.Name, []string{pd.Name}, pd.Fields)
+
+			statements := []ResourceDiff{}
+			for _, sd := range pd.Policies {
+				sd.Fields = c.unaccepted(rd.Name, []string{pd.Name, sd.Name}, sd.Fields)
+				if len(sd.Fields) > 0 {
+					statements = append(statements, sd)
+				}
+			}
+			pd.Policies = statements
+
+			if len(pd.Fields) > 0 || len(pd.Policies) > 0 {
+				policies = append(policies, pd)
+			}
+		}
+		rd.Policies = policies
+
+		documents := []ResourceDiff{}
+		for _, dd := range rd.Documents {
+			dd.Fields = c.unaccepted(rd.Name, []string{dd.Name}, dd.Fields)
+			if len(dd.Fields) > 0 {
+				documents = append(documents, dd)
+			}
+		}
+		rd.Documents = documents
+
+		if len(rd.Fields) > 0 || len(rd.Policies) > 0 || len(rd.Documents) > 0 {
+			diffs = append(diffs, rd)
+		}
+	}
+
+	d.Diffs = diffs
+
+	outputs := []ResourceDiff{}
+	for _, od := range d.OutputDiffs {
+		od.Fields = c.unaccepted("output."+od.Name, nil, od.Fields)
+		if len(od.Fields) > 0 {
+			outputs = append(outputs, od)
+		}
+	}
+	d.OutputDiffs = outputs
+}
+
+func (c Comparer) unaccepted(address string, prefix []string, fields []FieldDiff) []FieldDiff {
+	kept := []FieldDiff{}
+
+	for _, f := range fields {
+		path := fieldPath(prefix, f.Path)
+		if a := c.acceptedBy(address, path, f); a != nil {
+			*a.hits++
+			c.record(address, path, fmt.Sprint(f.OldValue), fmt.Sprint(f.NewValue), "baseline")
+			continue
+		}
+		kept = append(kept, f)
+	}
+
+	return kept
+}
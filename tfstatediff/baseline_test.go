@@ -0,0 +1,104 @@
+package tfstatediff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyBaseline(t *testing.T) {
+	stateDiff := func() *StateDiff {
+		return &StateDiff{
+			Diffs: []ResourceDiff{
+				{Name: "aws_instance.a", Fields: []FieldDiff{
+					{Path: "/instance_type", OldValue: `"t3.micro"`, NewValue: `"m5.large"`},
+					{Path: "/ami", OldValue: `"ami-1"`, NewValue: `"ami-2"`},
+				}},
+				{Name: "aws_iam_policy.p", Policies: []ResourceDiff{{Name: "/policy", Policies: []ResourceDiff{
+					{Name: "Statement Read", Change: ChangeChanged, Fields: []FieldDiff{{Path: "/Action", OldValue: `"s3:GetObject"`, NewValue: `"s3:*"`}}},
+				}}}},
+				{Name: "aws_ecs_task_definition.t", Documents: []ResourceDiff{{Name: "/container_definitions", Fields: []FieldDiff{
+					{Path: "/0/cpu", OldValue: "256", NewValue: "512"},
+				}}}},
+			},
+			OutputDiffs: []ResourceDiff{{Name: "url", Change: ChangeChanged, Fields: []FieldDiff{{Path: "", OldValue: `"a"`, NewValue: `"b"`}}}},
+		}
+	}
+
+	b := NewBaseline(&ComparisonResult{StateDiff: stateDiff()})
+	if len(b.Accepted) != 5 {
+		t.Fatalf("accepted: %+v", b.Accepted)
+	}
+	// paths are prefixed by the policy or document attribute and the statement
+	wantPaths := []string{"/instance_type", "/ami", "/policy Statement Read /Action", "/container_definitions /0/cpu", ""}
+	for i, a := range b.Accepted {
+		if a.Path != wantPaths[i] {
+			t.Errorf("accepted[%d]: %+v, want path %q", i, a, wantPaths[i])
+		}
+	}
+
+	tests := []struct {
+		name string
+		// indexes of the accepted diffs to keep in the baseline
+		accepted []int
+		// changes the diff after the baseline is written
+		change    func(d *StateDiff)
+		wantDiffs []string
+		unused    int
+	}{
+		{
+			name:      "all accepted",
+			accepted:  []int{0, 1, 2, 3, 4},
+			change:    func(d *StateDiff) {},
+			wantDiffs: []string{},
+		},
+		{
+			name:      "partly accepted",
+			accepted:  []int{1, 2},
+			change:    func(d *StateDiff) {},
+			wantDiffs: []string{"aws_instance.a /instance_type", "aws_ecs_task_definition.t /container_definitions /0/cpu", "output.url"},
+		},
+		{
+			name:     "value changed",
+			accepted: []int{0, 1, 2, 3, 4},
+			change: func(d *StateDiff) {
+				d.Diffs[0].Fields[0].NewValue = `"m5.xlarge"`
+				d.Diffs[2].Documents[0].Fields[0].OldValue = "128"
+			},
+			wantDiffs: []string{"aws_instance.a /instance_type", "aws_ecs_task_definition.t /container_definitions /0/cpu"},
+			unused:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(Config{}, TfProvidersSchema{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			accepted := Baseline{Accepted: []AcceptedDiff{}}
+			for _, i := range tt.accepted {
+				accepted.Accepted = append(accepted.Accepted, b.Accepted[i])
+			}
+			c.SetBaseline(accepted)
+
+			d := stateDiff()
+			tt.change(d)
+			c.applyBaseline(d)
+
+			got := []string{}
+			walkFields(d, func(address string, path string, f FieldDiff) {
+				got = append(got, fieldPath([]string{address}, path))
+			})
+			if !reflect.DeepEqual(got, tt.wantDiffs) {
+				t.Errorf("got %q, want %q", got, tt.wantDiffs)
+			}
+			if len(tt.wantDiffs) == 0 && (len(d.Diffs) > 0 || len(d.OutputDiffs) > 0) {
+				// resources, policies and documents without fields are removed
+				t.Errorf("empty diffs left: %+v %+v", d.Diffs, d.OutputDiffs)
+			}
+			if unused := c.UnusedRules(); len(unused) != tt.unused {
+				t.Errorf("unused: %v", unused)
+			}
+		})
+	}
+}
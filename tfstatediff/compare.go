@@ -0,0 +1,1022 @@
+// Package tfstatediff compares resources of terraform states and plans between two environments.
+package tfstatediff
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/koron/go-dproxy"
+	"github.com/wI2L/jsondiff"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	IgnorePattern []ConfigIgnorePattern        `yaml:"ignore_pattern"`
+	IgnoreDiff    []ConfigIgnoreDiff           `yaml:"ignore_diff"`
+	Placeholders  map[string]ConfigPlaceholder `yaml:"placeholders"`
+	AddressMap    []ConfigAddressMap           `yaml:"address_map"`
+	IdSources     []ConfigIdSource             `yaml:"id_sources"`
+	Documents     []ConfigDocument             `yaml:"documents"`
+
+	// rules enabled by default
+	IgnoreTags     ConfigIgnoreTags `yaml:"ignore_tags"`
+	IgnoreNull     *bool            `yaml:"ignore_null"`     // null and absent values are equal
+	IgnoreComputed *bool            `yaml:"ignore_computed"` // attributes computed by providers
+
+	IgnoreSingleElementList *bool `yaml:"ignore_single_element_list"` // a string equals a list of only the string
+}
+
+type ConfigIgnoreTags struct {
+	Enabled *bool    `yaml:"enabled"`
+	Keys    []string `yaml:"keys,omitempty"` // ignore only these tag keys
+}
+
+type ConfigIgnorePattern struct {
+	Address string `yaml:"address,omitempty"`
+	Path    string `yaml:"path,omitempty"`
+}
+
+type ConfigIgnoreDiff struct {
+	Left  string `yaml:"left"`
+	Right string `yaml:"right"`
+
+	// left is a regexp and right is its replacement (e.g. $1)
+	Regexp bool `yaml:"regexp,omitempty"`
+}
+
+// ConfigPlaceholder is a string differing between the environments, referred as {name} in ignore_diff
+type ConfigPlaceholder struct {
+	Left  string `yaml:"left"`
+	Right string `yaml:"right"`
+}
+
+type ConfigAddressMap struct {
+	// regexp to match a lefthand address and its replacement (e.g. $1)
+	Pattern string `yaml:"pattern,omitempty"`
+	Replace string `yaml:"replace,omitempty"`
+
+	// explicit pair of addresses
+	Left  string `yaml:"left,omitempty"`
+	Right string `yaml:"right,omitempty"`
+}
+
+// ConfigIdSource declares attributes whose values are referenced by other resources as IDs
+type ConfigIdSource struct {
+	Type        string   `yaml:"type,omitempty"`
+	TypePattern string   `yaml:"type_pattern,omitempty"`
+	Attributes  []string `yaml:"attributes"`
+
+	// whether the attribute is a list of IDs
+	List bool `yaml:"list,omitempty"`
+}
+
+// ConfigDocument declares attributes holding encoded documents to compare structurally
+type ConfigDocument struct {
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"` // json, yaml, base64 (text), base64+json or base64+yaml
+}
+
+type TfProvidersSchema struct {
+	FormatVersion  string                      `json:"format_version"`
+	ProviderSchema map[string]TfProviderSchema `json:"provider_schemas"`
+}
+
+type TfProviderSchema struct {
+	ResourceSchemas   map[string]TfSchema `json:"resource_schemas"`
+	DataSourceSchemas map[string]TfSchema `json:"data_source_schemas"`
+}
+
+type TfSchema struct {
+	Block TfSchemaBlock `json:"block"`
+
+	// only for block_types
+	NestingMode string `json:"nesting_mode,omitempty"`
+}
+
+type TfSchemaBlock struct {
+	Attributes map[string]TfSchemaAttribute `json:"attributes"`
+	BlockTypes map[string]TfSchema          `json:"block_types"`
+}
+
+type TfSchemaAttribute struct {
+	Type     any  `json:"type"` // string or []string
+	Required bool `json:"required"`
+	Optional bool `json:"optional"`
+	Computed bool `json:"computed"`
+}
+
+type IgnorePattern struct {
+	address *regexp.Regexp
+	path    *regexp.Regexp
+	hits    *int
+}
+
+type Comparer struct {
+	config         Config
+	ignorePattern  []IgnorePattern
+	ignoreDiff     []ignoreDiffRule
+	inL            idNormalizer
+	inR            idNormalizer
+	snL            schematicNormalizer
+	snR            schematicNormalizer
+	am             addressMapper
+	idSources      []idSource
+	documentRules  []documentRule
+	ignoreTags     bool
+	ignoreTagKeys  map[string]bool
+	ignoreNull     bool
+	ignoreComputed bool
+	ignoreSingle   bool
+	wDetail        io.Writer
+	keepGoing      bool
+	explain        bool
+	ex             *explanation
+	baseline       []acceptedDiffRule
+	showSensitive  bool
+	salt           []byte
+
+	// sensitive_values of both sides by left address
+	sensitive map[string][]map[string]any
+}
+
+// LoadConfig reads a YAML configuration file
+func LoadConfig(path string) (Config, error) {
+	c := Config{}
+
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err = yaml.Unmarshal(bytes, &c); err != nil {
+		return c, err
+	}
+
+	return c, nil
+}
+
+// LoadProvidersSchema reads the output of `terraform providers schema -json`
+func LoadProvidersSchema(path string) (TfProvidersSchema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TfProvidersSchema{}, err
+	}
+	defer f.Close()
+
+	return ReadProvidersSchema(f)
+}
+
+func ReadProvidersSchema(r io.Reader) (TfProvidersSchema, error) {
+	var ps TfProvidersSchema
+	if err := json.NewDecoder(r).Decode(&ps); err != nil {
+		return ps, err
+	}
+
+	return ps, nil
+}
+
+// MergeProvidersSchemas returns the union of schemas so that attributes in either provider version are known.
+// Definitions in a take precedence over b.
+func MergeProvidersSchemas(a TfProvidersSchema, b TfProvidersSchema) TfProvidersSchema {
+	merged := TfProvidersSchema{
+		FormatVersion:  a.FormatVersion,
+		ProviderSchema: map[string]TfProviderSchema{},
+	}
+
+	for k, v := range b.ProviderSchema {
+		merged.ProviderSchema[k] = v
+	}
+	for k, v := range a.ProviderSchema {
+		w, ok := merged.ProviderSchema[k]
+		if !ok {
+			merged.ProviderSchema[k] = v
+			continue
+		}
+		merged.ProviderSchema[k] = TfProviderSchema{
+			ResourceSchemas:   mergeSchemas(v.ResourceSchemas, w.ResourceSchemas),
+			DataSourceSchemas: mergeSchemas(v.DataSourceSchemas, w.DataSourceSchemas),
+		}
+	}
+
+	return merged
+}
+
+func mergeSchemas(a map[string]TfSchema, b map[string]TfSchema) map[string]TfSchema {
+	merged := map[string]TfSchema{}
+
+	for k, v := range b {
+		merged[k] = v
+	}
+	for k, v := range a {
+		if w, ok := merged[k]; ok {
+			merged[k] = mergeSchema(v, w)
+		} else {
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
+
+func mergeSchema(a TfSchema, b TfSchema) TfSchema {
+	attributes := map[string]TfSchemaAttribute{}
+	for k, v := range b.Block.Attributes {
+		attributes[k] = v
+	}
+	for k, v := range a.Block.Attributes {
+		attributes[k] = v
+	}
+
+	return TfSchema{
+		Block: TfSchemaBlock{
+			Attributes: attributes,
+			BlockTypes: mergeSchemas(a.Block.BlockTypes, b.Block.BlockTypes),
+		},
+		NestingMode: a.NestingMode,
+	}
+}
+
+// New creates a Comparer using the same providers schema for both sides
+func New(c Config, ps TfProvidersSchema) (*Comparer, error) {
+	return NewWithSchemas(c, ps, ps)
+}
+
+// NewWithSchemas creates a Comparer with providers schemas for each side
+func NewWithSchemas(c Config, psL TfProvidersSchema, psR TfProvidersSchema) (*Comparer, error) {
+	ip := make([]IgnorePattern, len(c.IgnorePattern))
+	for i := range c.IgnorePattern {
+		ip[i].hits = new(int)
+		if c.IgnorePattern[i].Address != "" {
+			re, err := regexp.Compile(c.IgnorePattern[i].Address)
+			if err != nil {
+				return nil, err
+			}
+			ip[i].address = re
+		}
+		if c.IgnorePattern[i].Path != "" {
+			re, err := regexp.Compile(c.IgnorePattern[i].Path)
+			if err != nil {
+				return nil, err
+			}
+			ip[i].path = re
+		}
+	}
+
+	ignoreDiff, err := newIgnoreDiffRules(c.IgnoreDiff, c.Placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	am, err := newAddressMapper(c.AddressMap)
+	if err != nil {
+		return nil, err
+	}
+
+	idSources, err := newIdSources(c.IdSources)
+	if err != nil {
+		return nil, err
+	}
+
+	documentRules, err := newDocumentRules(c.Documents)
+	if err != nil {
+		return nil, err
+	}
+
+	var ignoreTagKeys map[string]bool
+	if len(c.IgnoreTags.Keys) > 0 {
+		ignoreTagKeys = map[string]bool{}
+		for _, k := range c.IgnoreTags.Keys {
+			ignoreTagKeys[k] = true
+		}
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &Comparer{
+		config:         c,
+		ignorePattern:  ip,
+		ignoreDiff:     ignoreDiff,
+		snL:            newSchematicNormalizer(ignoreDiff, psL),
+		snR:            newSchematicNormalizer(ignoreDiff, psR),
+		am:             am,
+		idSources:      idSources,
+		documentRules:  documentRules,
+		ignoreTags:     enabled(c.IgnoreTags.Enabled),
+		ignoreTagKeys:  ignoreTagKeys,
+		ignoreNull:     enabled(c.IgnoreNull),
+		ignoreComputed: enabled(c.IgnoreComputed),
+		ignoreSingle:   enabled(c.IgnoreSingleElementList),
+		wDetail:        ioutil.Discard,
+		salt:           salt,
+	}, nil
+}
+
+// enabled returns the value of an optional flag which defaults to true
+func enabled(b *bool) bool {
+	return b == nil || *b
+}
+
+func (c *Comparer) SetDetailWriter(w io.Writer) {
+	c.wDetail = w
+}
+
+// SetKeepGoing makes the comparison record per-resource errors in StateDiff.Errors instead of failing
+func (c *Comparer) SetKeepGoing(keepGoing bool) {
+	c.keepGoing = keepGoing
+}
+
+// see https://www.terraform.io/internals/json-format
+
+type TfState struct {
+	FormatVersion    string    `json:"format_version"`
+	TerraformVersion string    `json:"terraform_version"`
+	Values           *TfValues `json:"values"`
+}
+
+type TfStatePlan struct {
+	TfState
+	PriorState    *TfState  `json:"prior_state"`
+	PlannedValues *TfValues `json:"planned_values"`
+}
+
+type TfValues struct {
+	Outputs    map[string]TfOutput `json:"outputs,omitempty"`
+	RootModule TfModule            `json:"root_module"`
+}
+
+type TfModule struct {
+	Address      string       `json:"address,omitempty"`
+	Resources    []TfResource `json:"resources"`
+	ChildModules []TfModule   `json:"child_modules,omitempty"`
+}
+
+// resources returns the resources of the module and all its descendants
+func (m TfModule) resources() []TfResource {
+	rs := append([]TfResource{}, m.Resources...)
+
+	for i := range m.ChildModules {
+		rs = append(rs, m.ChildModules[i].resources()...)
+	}
+
+	return rs
+}
+
+type TfResource struct {
+	Address      string         `json:"address"`
+	Mode         string         `json:"mode"`
+	Type         string         `json:"type"`
+	Name         string         `json:"name"`
+	ProviderName string         `json:"provider_name"`
+	Values       map[string]any `json:"values"`
+
+	// true at sensitive values, or at objects and lists all of which are sensitive
+	SensitiveValues map[string]any `json:"sensitive_values,omitempty"`
+}
+
+type ComparisonResult struct {
+	StateDiff *StateDiff `json:"state_diff"`
+	PlanDiff  *StateDiff `json:"plan_diff,omitempty"`
+}
+
+// Print writes the summary of the result
+func (cr ComparisonResult) Print(w io.Writer) {
+	d, p := cr.StateDiff, cr.PlanDiff
+
+	if p != nil {
+		fmt.Fprintf(w, "common resources:    %6d (%+4d)\n", p.Common, p.Common-d.Common)
+		fmt.Fprintf(w, "resources with diff: %6d (%+4d)\n", len(p.Diffs), len(p.Diffs)-len(d.Diffs))
+		fmt.Fprintf(w, "left only resources: %6d (%+4d)\n", len(p.LeftOnly), len(p.LeftOnly)-len(d.LeftOnly))
+		fmt.Fprintf(w, "right only resources:%6d (%+4d)\n", len(p.RightOnly), len(p.RightOnly)-len(d.RightOnly))
+		fmt.Fprintf(w, "outputs with diff:   %6d (%+4d)\n", len(p.OutputDiffs), len(p.OutputDiffs)-len(d.OutputDiffs))
+	} else {
+		fmt.Fprintf(w, "common resources:    %6d\n", d.Common)
+		fmt.Fprintf(w, "resources with diff: %6d\n", len(d.Diffs))
+		fmt.Fprintf(w, "left only resources: %6d\n", len(d.LeftOnly))
+		fmt.Fprintf(w, "right only resources:%6d\n", len(d.RightOnly))
+		fmt.Fprintf(w, "outputs with diff:   %6d\n", len(d.OutputDiffs))
+	}
+
+	errs := len(d.Errors)
+	if p != nil {
+		errs += len(p.Errors)
+	}
+	if errs > 0 {
+		fmt.Fprintf(w, "errors:              %6d\n", errs)
+	}
+}
+
+// CompareReaders compares tfstates or plans read from l and r
+func (c Comparer) CompareReaders(l io.Reader, r io.Reader) (*ComparisonResult, error) {
+	spL, err := ReadState(l)
+	if err != nil {
+		return nil, err
+	}
+
+	spR, err := ReadState(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Compare(spL, spR)
+}
+
+// Compare compares tfstates or plans. For plans, both prior states and planned values are compared.
+func (c Comparer) Compare(spL *TfStatePlan, spR *TfStatePlan) (*ComparisonResult, error) {
+	isPlanL := spL.PlannedValues != nil
+	isPlanR := spR.PlannedValues != nil
+
+	var valuesL, valuesR *TfValues
+
+	if isPlanL {
+		if spL.PriorState != nil {
+			valuesL = spL.PriorState.Values
+		}
+	} else {
+		valuesL = spL.TfState.Values
+	}
+
+	if isPlanR {
+		if spR.PriorState != nil {
+			valuesR = spR.PriorState.Values
+		}
+	} else {
+		valuesR = spR.TfState.Values
+	}
+
+	diff, err := c.compareValues(orEmpty(valuesL), orEmpty(valuesR))
+	if err != nil {
+		return nil, err
+	}
+
+	result := ComparisonResult{StateDiff: diff, PlanDiff: nil}
+
+	if isPlanL || isPlanR {
+		if isPlanL {
+			valuesL = spL.PlannedValues
+		}
+		if isPlanR {
+			valuesR = spR.PlannedValues
+		}
+
+		planDiff, err := c.compareValues(orEmpty(valuesL), orEmpty(valuesR))
+		if err != nil {
+			return nil, err
+		}
+
+		result.PlanDiff = planDiff
+	}
+
+	return &result, nil
+}
+
+// orEmpty returns empty values for nil, e.g. of an empty state which has no values
+func orEmpty(v *TfValues) TfValues {
+	if v == nil {
+		return TfValues{RootModule: TfModule{Resources: []TfResource{}}}
+	}
+	return *v
+}
+
+type StateDiff struct {
+	Common    int            `json:"common"`
+	Diffs     []ResourceDiff `json:"resource_diffs"`
+	LeftOnly  []string       `json:"left_only"`
+	RightOnly []string       `json:"right_only"`
+
+	// root module outputs; Change is added, removed or changed
+	OutputDiffs []ResourceDiff `json:"output_diffs"`
+
+	// only with keep-going
+	Errors []ResourceError `json:"errors,omitempty"`
+
+	// only with explain
+	Suppressed []SuppressedDiff `json:"suppressed,omitempty"`
+}
+
+type ResourceError struct {
+	Address string `json:"address"`
+	Message string `json:"message"`
+}
+
+type ResourceDiff struct {
+	Name      string         `json:"name"`
+	Change    string         `json:"change,omitempty"` // only for policy statements: added, removed or changed
+	Fields    []FieldDiff    `json:"fields,omitempty"`
+	Policies  []ResourceDiff `json:"policies,omitempty"`  // policies of a resource, or statements of a policy
+	Documents []ResourceDiff `json:"documents,omitempty"` // attributes holding encoded documents
+}
+
+type FieldDiff struct {
+	Path     string `json:"path"`
+	OldValue any    `json:"old_value"`
+	NewValue any    `json:"new_value"`
+
+	// hashes of masked sensitive values
+	oldHash string
+	newHash string
+}
+
+func (c Comparer) compareValues(l TfValues, r TfValues) (*StateDiff, error) {
+	rsL := l.RootModule.resources()
+	rsR := r.RootModule.resources()
+
+	// references in left should resolve to the addresses in right
+	c.inL = newIdNormalizer(c.am.mapResources(rsL), c.idSources, c.wDetail)
+	c.inR = newIdNormalizer(rsR, c.idSources, c.wDetail)
+	if c.explain {
+		c.ex = newExplanation(rsL, rsR)
+	}
+	c.sensitive = map[string][]map[string]any{}
+	normalizedL, errsL, err := c.normalizeResources(c.inL, c.snL, rsL)
+	if err != nil {
+		return nil, err
+	}
+	normalizedR, errsR, err := c.normalizeResources(c.inR, c.snR, rsR)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := c.compareResources(normalizedL, normalizedR, errsL, errsR)
+	if err != nil {
+		return nil, err
+	}
+
+	if diff.OutputDiffs, err = c.compareOutputs(l.Outputs, r.Outputs); err != nil {
+		return nil, err
+	}
+
+	c.applyBaseline(diff)
+
+	if c.ex != nil {
+		diff.Suppressed = c.ex.suppressed
+	}
+
+	return diff, nil
+}
+
+// normalizeResources returns errors by address instead of failing when keep-going
+func (c Comparer) normalizeResources(in idNormalizer, sn schematicNormalizer, rs []TfResource) ([]TfResource, map[string]error, error) {
+	normalizedResources := make([]TfResource, len(rs))
+	errs := map[string]error{}
+
+	for i := range rs {
+		r := rs[i]
+
+		values := in.normalize(r.Values)
+
+		nr := TfResource{
+			Address:         r.Address,
+			Mode:            r.Mode,
+			Type:            r.Type,
+			Name:            r.Name,
+			ProviderName:    r.ProviderName,
+			Values:          values,
+			SensitiveValues: r.SensitiveValues,
+		}
+
+		normalized, err := sn.normalize(nr)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", r.Address, err)
+			if !c.keepGoing {
+				return nil, nil, err
+			}
+			errs[r.Address] = err
+		}
+		normalizedResources[i] = normalized
+	}
+
+	return normalizedResources, errs, nil
+}
+
+func (c Comparer) compareResources(l []TfResource, r []TfResource, errsL map[string]error, errsR map[string]error) (*StateDiff, error) {
+	diffs := []ResourceDiff{}
+	errs := []ResourceError{}
+
+	// index right resources by normalized address; the first one wins on duplicates
+	indexR := make(map[string]int, len(r))
+	for j := range r {
+		a := addressNormalize(r[j].Address)
+		if _, ok := indexR[a]; !ok {
+			indexR[a] = j
+		}
+	}
+
+	leftOnly := []string{}
+	foundR := map[int]bool{}
+
+	for i := range l {
+		address, rule := c.am.mapLeft(l[i].Address)
+		j, ok := indexR[addressNormalize(address)]
+		if !ok {
+			leftOnly = append(leftOnly, l[i].Address)
+			if err := errsL[l[i].Address]; err != nil {
+				errs = append(errs, ResourceError{Address: l[i].Address, Message: err.Error()})
+			}
+			continue
+		}
+
+		if rule != nil {
+			*rule.hits++
+			fmt.Fprintf(c.wDetail, "compare %s with %s (address_map: %s)\n", l[i].Address, r[j].Address, rule)
+		} else {
+			fmt.Fprintf(c.wDetail, "compare %s\n", l[i].Address)
+		}
+
+		c.sensitive[l[i].Address] = []map[string]any{l[i].SensitiveValues, r[j].SensitiveValues}
+
+		err := errsL[l[i].Address]
+		if err == nil {
+			err = errsR[r[j].Address]
+		}
+		var rd *ResourceDiff
+		if err == nil {
+			rd, err = c.compareResource(l[i], r[j])
+		}
+
+		if err != nil {
+			if !c.keepGoing {
+				return nil, err
+			}
+			fmt.Fprintf(c.wDetail, "  error: %s\n", err)
+			errs = append(errs, ResourceError{Address: l[i].Address, Message: err.Error()})
+		} else if len(rd.Fields) > 0 || len(rd.Policies) > 0 || len(rd.Documents) > 0 {
+			diffs = append(diffs, *rd)
+		}
+
+		fmt.Fprintln(c.wDetail, "")
+
+		foundR[j] = true
+	}
+
+	fmt.Fprintln(c.wDetail, "Left not compared:")
+	for i := range leftOnly {
+		fmt.Fprintf(c.wDetail, "%s\n", leftOnly[i])
+	}
+
+	rightOnly := []string{}
+	fmt.Fprintln(c.wDetail, "")
+	fmt.Fprintln(c.wDetail, "Right not compared:")
+	for j := range r {
+		if !foundR[j] {
+			fmt.Fprintf(c.wDetail, "%s\n", r[j].Address)
+			rightOnly = append(rightOnly, r[j].Address)
+			if err := errsR[r[j].Address]; err != nil {
+				errs = append(errs, ResourceError{Address: r[j].Address, Message: err.Error()})
+			}
+		}
+	}
+
+	fmt.Fprintln(c.wDetail, "")
+
+	return &StateDiff{
+		Common:    len(foundR),
+		Diffs:     diffs,
+		LeftOnly:  leftOnly,
+		RightOnly: rightOnly,
+		Errors:    errs,
+	}, nil
+}
+
+func (c Comparer) compareResource(l TfResource, r TfResource) (*ResourceDiff, error) {
+	sL, err := c.snL.findSchema(l)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.Address, err)
+	}
+	sR, err := c.snR.findSchema(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Address, err)
+	}
+
+	patch, err := jsondiff.CompareOpts(l.Values, r.Values, jsondiff.Equivalent())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.explainNormalization(l, r, sL, patch); err != nil {
+		return nil, err
+	}
+
+	rd := ResourceDiff{Name: l.Address}
+
+	for k := range patch {
+		path := patch[k].Path.String()
+		if rule := c.ignoredBy(l.Address, "", path, patch[k].OldValue, patch[k].Value); rule != "" {
+			c.suppress(l.Address, path, patch[k].OldValue, patch[k].Value, rule)
+			continue
+		}
+		// attributes added in a newer provider exist only in one side
+		isArg, err := isArgument(sL, path[1:])
+		if err != nil {
+			isArg, err = isArgument(sR, path[1:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.Address, err)
+		}
+		if !isArg && c.ignoreComputed {
+			c.suppress(l.Address, path, patch[k].OldValue, patch[k].Value, "ignore_computed")
+			continue
+		}
+		if c.isSensitive(l.Address, path) {
+			// decoded policies and documents would reveal the values
+			f := c.sensitiveField(path, serializeOrRaw(patch[k].OldValue), serializeOrRaw(patch[k].Value))
+			fmt.Fprintf(c.wDetail, "  %s : %s -> %s\n", path, f.OldValue, f.NewValue)
+			rd.Fields = append(rd.Fields, f)
+		} else if strings.HasSuffix(path, "/policy") || strings.HasSuffix(path, "/inline_policy") || strings.HasSuffix(path, "/assume_role_policy") {
+			pd, err := c.comparePolicy(path, l, r)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", l.Address, err)
+			}
+			if len(pd.Fields) > 0 || len(pd.Policies) > 0 {
+				rd.Policies = append(rd.Policies, *pd)
+			}
+		} else if decode := c.findDocumentDecoder(path, patch[k].OldValue, patch[k].Value); decode != nil {
+			dd, err := c.compareDocument(l.Address, path, patch[k].OldValue, patch[k].Value, decode)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", l.Address, err)
+			}
+			if len(dd.Fields) > 0 {
+				rd.Documents = append(rd.Documents, *dd)
+			}
+		} else {
+			old, err := serialize(patch[k].OldValue)
+			if err != nil {
+				return nil, err
+			}
+			new, err := serialize(patch[k].Value)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(c.wDetail, "  %s : %s -> %s\n", path, old, new)
+			rd.Fields = append(rd.Fields, FieldDiff{Path: path, OldValue: old, NewValue: new})
+		}
+	}
+
+	return &rd, nil
+}
+
+func (c Comparer) comparePolicy(path string, l TfResource, r TfResource) (*ResourceDiff, error) {
+	fmt.Fprintf(c.wDetail, "  compare %s:\n", path)
+	pd := ResourceDiff{Name: path}
+
+	v, err := dproxy.Pointer(l.Values, path).String()
+	if err != nil {
+		return nil, err
+	}
+	if v == "" {
+		v = "{}"
+	}
+
+	w, err := dproxy.Pointer(r.Values, path).String()
+	if err != nil {
+		return nil, err
+	}
+	if w == "" {
+		w = "{}"
+	}
+
+	var dataL map[string]any
+	err = json.Unmarshal([]byte(v), &dataL)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataR map[string]any
+	err = json.Unmarshal([]byte(w), &dataR)
+	if err != nil {
+		return nil, err
+	}
+
+	dataL = c.inL.normalize(dataL)
+	dataR = c.inR.normalize(dataR)
+
+	stsL, okL, err := c.snL.parsePolicyStatements(dataL)
+	if err != nil {
+		return nil, err
+	}
+	stsR, okR, err := c.snR.parsePolicyStatements(dataR)
+	if err != nil {
+		return nil, err
+	}
+	if okL && okR {
+		// compare statements semantically and the rest (e.g. Version) structurally
+		pd.Policies, err = c.comparePolicyStatements(l.Address, path, stsL, stsR)
+		if err != nil {
+			return nil, err
+		}
+		dataL = withoutStatement(dataL)
+		dataR = withoutStatement(dataR)
+	}
+
+	patch, err := jsondiff.CompareOpts(dataL, dataR, jsondiff.Equivalent())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range patch {
+		p := patch[i].Path.String()
+
+		if rule := c.ignoredBy(l.Address, path, p, patch[i].OldValue, patch[i].Value); rule != "" {
+			c.suppress(l.Address, path+p, patch[i].OldValue, patch[i].Value, rule)
+			continue
+		}
+
+		old, err := serialize(patch[i].OldValue)
+		if err != nil {
+			return nil, err
+		}
+		new, err := serialize(patch[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(c.wDetail, "    %s : %s -> %s\n", p, old, new)
+		pd.Fields = append(pd.Fields, FieldDiff{Path: p, OldValue: old, NewValue: new})
+	}
+
+	return &pd, nil
+}
+
+// ignoredBy returns the rule by which the diff is ignored, or "" if not ignored
+func (c Comparer) ignoredBy(address string, basePath string, path string, oldValue any, newValue any) string {
+	fullPath := basePath + path
+	for i := range c.ignorePattern {
+		if (c.ignorePattern[i].address == nil || c.ignorePattern[i].address.MatchString(address)) && (c.ignorePattern[i].path == nil || c.ignorePattern[i].path.MatchString(fullPath)) {
+			*c.ignorePattern[i].hits++
+			return fmt.Sprintf("ignore_pattern[%d]", i)
+		}
+	}
+
+	if c.ignoreTags && c.isIgnorableTag(path) {
+		return "ignore_tags"
+	}
+
+	if c.ignoreNull && oldValue == nil && newValue == nil {
+		return "ignore_null"
+	}
+
+	// a string and a single-element list differ unless ignore_single_element_list
+	_, oldIsString := oldValue.(string)
+	_, newIsString := newValue.(string)
+	if oldIsString != newIsString && !c.ignoreSingle {
+		return ""
+	}
+
+	old, ok := oldValue.(string)
+	if !ok {
+		if olds, ok := oldValue.([]any); ok && len(olds) == 1 {
+			if old, ok = olds[0].(string); !ok {
+				return ""
+			}
+		} else {
+			return ""
+		}
+	}
+	new, ok := newValue.(string)
+	if !ok {
+		if news, ok := newValue.([]any); ok && len(news) == 1 {
+			if new, ok = news[0].(string); !ok {
+				return ""
+			}
+		} else {
+			return ""
+		}
+	}
+
+	// indexes of matched rules, counted as hits only if the whole values match
+	used := []int{}
+	i, j := 0, 0
+outer:
+	for i < len(old) && j < len(new) {
+		for k := range c.ignoreDiff {
+			if di, dj, ok := c.ignoreDiff[k].match(old[i:], new[j:]); ok && di+dj > 0 {
+				i += di
+				j += dj
+				used = append(used, k)
+				continue outer
+			}
+		}
+		if old[i] != new[j] {
+			return ""
+		}
+		i, j = i+1, j+1
+	}
+
+	if i != len(old) || j != len(new) {
+		return ""
+	}
+	if len(used) == 0 {
+		return "ignore_single_element_list"
+	}
+	names := make([]string, len(used))
+	for n, k := range used {
+		*c.ignoreDiff[k].hits++
+		names[n] = c.ignoreDiff[k].String()
+	}
+	return fmt.Sprintf("ignore_diff (%s)", strings.Join(names, ", "))
+}
+
+func (c Comparer) isIgnorableTag(path string) bool {
+	for _, prefix := range []string{"/tags/", "/tags_all/"} {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		if c.ignoreTagKeys == nil {
+			return true
+		}
+		key := strings.SplitN(path[len(prefix):], "/", 2)[0]
+		// unescape JSON pointer
+		key = strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")
+		return c.ignoreTagKeys[key]
+	}
+	return false
+}
+
+func isArgument(s TfSchema, path string) (bool, error) {
+	i := strings.Index(path, "/")
+
+	var p string
+	if i < 0 {
+		p = path
+	} else {
+		p = path[:i]
+	}
+
+	if a, ok := s.Block.Attributes[p]; ok {
+		if p == "id" {
+			// exception for id
+			return !a.Computed, nil
+		}
+		return !a.Computed || a.Optional, nil
+	}
+
+	if a, ok := s.Block.BlockTypes[p]; ok {
+		j := strings.Index(path[i+1:], "/")
+		if j < 0 {
+			// schema with sub-blocks should be a manual argument
+			return true, nil
+		}
+		return isArgument(a, path[i+1+j+1:])
+	}
+	return false, fmt.Errorf("schema attribute not found: %s", path)
+}
+
+func addressNormalize(address string) string {
+	return strings.ReplaceAll(address, "-", "_")
+}
+
+func serialize(val any) (string, error) {
+	s, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+
+	return string(s), nil
+}
+
+// LoadState reads the output of `terraform show -json` or a raw state file
+func LoadState(path string) (*TfStatePlan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadState(f)
+}
+
+// ReadState reads the output of `terraform show -json` or a raw state file (terraform.tfstate)
+func ReadState(r io.Reader) (*TfStatePlan, error) {
+	bytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var data TfStatePlan
+	if err = json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+
+	if data.FormatVersion == "" && data.Values == nil && data.PlannedValues == nil {
+		var raw tfRawState
+		if err = json.Unmarshal(bytes, &raw); err != nil {
+			return nil, err
+		}
+		if raw.Version == 0 {
+			return nil, fmt.Errorf("neither terraform show -json output nor terraform.tfstate")
+		}
+		state, err := raw.toState()
+		if err != nil {
+			return nil, err
+		}
+		data.TfState = *state
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,295 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fixtures shared by the tests and the benchmark
+const testProvider = "registry.terraform.io/hashicorp/aws"
+
+func testSchema() TfProvidersSchema {
+	return TfProvidersSchema{
+		ProviderSchema: map[string]TfProviderSchema{
+			testProvider: {
+				ResourceSchemas: map[string]TfSchema{
+					"aws_instance": {
+						Block: TfSchemaBlock{
+							Attributes: map[string]TfSchemaAttribute{
+								"id":            {Type: "string", Computed: true},
+								"arn":           {Type: "string", Computed: true},
+								"instance_type": {Type: "string", Optional: true},
+								"subnet_id":     {Type: "string", Optional: true},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// testState is a state of the resources in the root module
+func testState(rs ...TfResource) *TfStatePlan {
+	return &TfStatePlan{TfState: TfState{Values: &TfValues{RootModule: TfModule{Resources: rs}}}}
+}
+
+// testInstance is an aws_instance of testSchema
+func testInstance(name string, instanceType string) TfResource {
+	return TfResource{
+		Address:      "aws_instance." + name,
+		Mode:         "managed",
+		Type:         "aws_instance",
+		Name:         name,
+		ProviderName: testProvider,
+		Values:       map[string]any{"id": "i-" + name, "instance_type": instanceType},
+	}
+}
+
+// benchmarkValues generates n instances, of which every 10th has a different instance type between the sides
+func benchmarkValues(n int, env string) TfValues {
+	rs := make([]TfResource, n)
+	for i := range rs {
+		instanceType := "t3.micro"
+		if env == "prod" && i%10 == 0 {
+			instanceType = "m5.large"
+		}
+		id := fmt.Sprintf("i-%s-%d", env, i)
+		rs[i] = TfResource{
+			Address:      fmt.Sprintf("aws_instance.a[%d]", i),
+			Mode:         "managed",
+			Type:         "aws_instance",
+			Name:         "a",
+			ProviderName: testProvider,
+			Values: map[string]any{
+				"id":            id,
+				"arn":           "arn:aws:ec2:instance/" + id,
+				"instance_type": instanceType,
+				"subnet_id":     fmt.Sprintf("subnet-%d", i%16),
+			},
+		}
+	}
+	return TfValues{RootModule: TfModule{Resources: rs}}
+}
+
+func BenchmarkCompareResources(b *testing.B) {
+	for _, n := range []int{1000, 8000} {
+		b.Run(fmt.Sprintf("N=%d", n), func(b *testing.B) {
+			c, err := New(Config{}, testSchema())
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			l, r := benchmarkValues(n, "stg"), benchmarkValues(n, "prod")
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				d, err := c.compareValues(l, r)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if d.Common != n || len(d.Diffs) != n/10 {
+					b.Fatalf("unexpected result: %d common, %d diffs", d.Common, len(d.Diffs))
+				}
+			}
+		})
+	}
+}
+
+func TestCompareReadersEmptyState(t *testing.T) {
+	c, err := New(Config{}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left := `{"format_version":"1.0"}`
+	right := `{"format_version":"1.0","values":{"root_module":{"resources":[{"address":"aws_instance.a","mode":"managed","type":"aws_instance","name":"a","provider_name":"registry.terraform.io/hashicorp/aws","values":{"id":"i-1"}}]}}}`
+
+	result, err := c.CompareReaders(strings.NewReader(left), strings.NewReader(right))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.StateDiff.RightOnly) != 1 {
+		t.Errorf("right only: %v", result.StateDiff.RightOnly)
+	}
+
+	if _, err := c.CompareReaders(strings.NewReader(`{"foo":1}`), strings.NewReader(right)); err == nil {
+		t.Error("no error for unknown format")
+	}
+}
+
+func TestCompareKeepGoingReportsUnpairedErrors(t *testing.T) {
+	c, err := New(Config{}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetKeepGoing(true)
+
+	unknown := func(address string) TfResource {
+		return TfResource{Address: address, Mode: "managed", Type: "aws_unknown", Name: "x", ProviderName: testProvider, Values: map[string]any{}}
+	}
+	l := TfValues{RootModule: TfModule{Resources: []TfResource{unknown("aws_unknown.l")}}}
+	r := TfValues{RootModule: TfModule{Resources: []TfResource{unknown("aws_unknown.r")}}}
+
+	d, err := c.compareValues(l, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := []string{}
+	for _, e := range d.Errors {
+		addresses = append(addresses, e.Address)
+	}
+	if strings.Join(addresses, ",") != "aws_unknown.l,aws_unknown.r" {
+		t.Errorf("errors: %v", d.Errors)
+	}
+}
+
+func TestIgnoreSingleElementList(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name   string
+		config Config
+		old    any
+		new    any
+		want   string
+	}{
+		{"enabled by default", Config{}, "a", []any{"a"}, "ignore_single_element_list"},
+		{"different values", Config{}, "a", []any{"b"}, ""},
+		{"disabled", Config{IgnoreSingleElementList: &disabled}, []any{"a"}, "a", ""},
+		{"disabled with ignore_diff", Config{IgnoreSingleElementList: &disabled, IgnoreDiff: []ConfigIgnoreDiff{{Left: "stg", Right: "prod"}}}, "stg-a", []any{"prod-a"}, ""},
+		{"strings with ignore_diff", Config{IgnoreSingleElementList: &disabled, IgnoreDiff: []ConfigIgnoreDiff{{Left: "stg", Right: "prod"}}}, "stg-a", "prod-a", `ignore_diff ("stg" = "prod")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.config, testSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.ignoredBy("aws_instance.a", "", "/name", tt.old, tt.new); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreTagsAndNull(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name   string
+		config Config
+		path   string
+		old    any
+		new    any
+		want   string
+	}{
+		{"tags by default", Config{}, "/tags/Name", "a", "b", "ignore_tags"},
+		{"tags_all by default", Config{}, "/tags_all/Name", "a", "b", "ignore_tags"},
+		{"tags disabled", Config{IgnoreTags: ConfigIgnoreTags{Enabled: &disabled}}, "/tags/Name", "a", "b", ""},
+		{"tag key listed", Config{IgnoreTags: ConfigIgnoreTags{Keys: []string{"Name"}}}, "/tags/Name", "a", "b", "ignore_tags"},
+		{"tag key not listed", Config{IgnoreTags: ConfigIgnoreTags{Keys: []string{"Name"}}}, "/tags/Env", "a", "b", ""},
+		{"escaped tag key", Config{IgnoreTags: ConfigIgnoreTags{Keys: []string{"aws:cloudformation/stack"}}}, "/tags/aws:cloudformation~1stack", "a", "b", "ignore_tags"},
+		{"null by default", Config{}, "/name", nil, nil, "ignore_null"},
+		{"null disabled", Config{IgnoreNull: &disabled}, "/name", nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.config, testSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.ignoredBy("aws_instance.a", "", tt.path, tt.old, tt.new); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnusedRulesIgnoreDiffPartialMatch(t *testing.T) {
+	c, err := New(Config{IgnoreDiff: []ConfigIgnoreDiff{{Left: "stg", Right: "prod"}}}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rule matches a prefix, but the diff is reported
+	if rule := c.ignoredBy("aws_instance.a", "", "/name", "stg-a", "prod-b"); rule != "" {
+		t.Fatalf("ignored by %s", rule)
+	}
+	if unused := c.UnusedRules(); len(unused) != 1 {
+		t.Errorf("unused rules: %v", unused)
+	}
+
+	if rule := c.ignoredBy("aws_instance.a", "", "/name", "stg-a", "prod-a"); rule == "" {
+		t.Fatal("not ignored")
+	}
+	if unused := c.UnusedRules(); len(unused) != 0 {
+		t.Errorf("unused rules: %v", unused)
+	}
+}
+
+func TestUnusedRulesPlaceholders(t *testing.T) {
+	c, err := New(Config{
+		Placeholders: map[string]ConfigPlaceholder{"env": {Left: "stg", Right: "prod"}},
+		IgnoreDiff:   []ConfigIgnoreDiff{{Left: "role/{env}-", Right: "role/{env}-"}},
+	}, TfProvidersSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rule := c.ignoredBy("aws_iam_role.a", "", "/name", "role/stg-a", "role/prod-a"); rule == "" {
+		t.Fatal("not ignored")
+	}
+	if unused := c.UnusedRules(); len(unused) != 0 {
+		t.Errorf("unused rules: %v", unused)
+	}
+}
+
+func TestModuleResources(t *testing.T) {
+	resource := func(address string) TfResource {
+		return TfResource{Address: address}
+	}
+
+	tests := []struct {
+		name   string
+		module TfModule
+		want   []string
+	}{
+		{"empty", TfModule{}, []string{}},
+		{"root only", TfModule{Resources: []TfResource{resource("aws_vpc.a")}}, []string{"aws_vpc.a"}},
+		{
+			name: "nested",
+			module: TfModule{
+				Resources: []TfResource{resource("aws_vpc.a")},
+				ChildModules: []TfModule{
+					{
+						Address:   "module.x",
+						Resources: []TfResource{resource("module.x.aws_subnet.a")},
+						ChildModules: []TfModule{
+							{Address: "module.x.module.y", Resources: []TfResource{resource("module.x.module.y.aws_subnet.b")}},
+						},
+					},
+					// modules without their own resources
+					{Address: "module.z", ChildModules: []TfModule{{Address: "module.z.module.w", Resources: []TfResource{resource("module.z.module.w.aws_subnet.c")}}}},
+				},
+			},
+			want: []string{"aws_vpc.a", "module.x.aws_subnet.a", "module.x.module.y.aws_subnet.b", "module.z.module.w.aws_subnet.c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, r := range tt.module.resources() {
+				got = append(got, r.Address)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package tfstatediff
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/wI2L/jsondiff"
+	"gopkg.in/yaml.v3"
+)
+
+// documentDecoder parses an attribute value encoded in a string
+type documentDecoder func(s string) (any, error)
+
+var documentDecoders = map[string]documentDecoder{
+	"json":        decodeJson,
+	"yaml":        decodeYaml,
+	"base64":      decodeBase64(decodeLines),
+	"base64+json": decodeBase64(decodeJson),
+	"base64+yaml": decodeBase64(decodeYaml),
+}
+
+type documentRule struct {
+	path   *regexp.Regexp
+	decode documentDecoder
+}
+
+func newDocumentRules(c []ConfigDocument) ([]documentRule, error) {
+	rules := make([]documentRule, len(c))
+
+	for i := range c {
+		re, err := regexp.Compile(c[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		d, ok := documentDecoders[c[i].Format]
+		if !ok {
+			return nil, fmt.Errorf("documents[%d]: unknown format: %s", i, c[i].Format)
+		}
+		rules[i] = documentRule{path: re, decode: d}
+	}
+
+	return rules, nil
+}
+
+func decodeJson(s string) (any, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decodeYaml parses YAML, which may contain multiple documents (e.g. Kubernetes manifests)
+func decodeYaml(s string) (any, error) {
+	docs := []any{}
+
+	d := yaml.NewDecoder(strings.NewReader(s))
+	for {
+		var data any
+		if err := d.Decode(&data); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		v, err := convertYaml(data)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, v)
+	}
+
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// convertYaml converts values decoded by yaml into the same types as encoding/json
+func convertYaml(v any) (any, error) {
+	switch t := v.(type) {
+	case map[string]any:
+		m := map[string]any{}
+		for k, val := range t {
+			cv, err := convertYaml(val)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = cv
+		}
+		return m, nil
+	case map[any]any:
+		m := map[string]any{}
+		for k, val := range t {
+			cv, err := convertYaml(val)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = cv
+		}
+		return m, nil
+	case []any:
+		a := make([]any, len(t))
+		for i := range t {
+			cv, err := convertYaml(t[i])
+			if err != nil {
+				return nil, err
+			}
+			a[i] = cv
+		}
+		return a, nil
+	case int:
+		return float64(t), nil
+	}
+	return v, nil
+}
+
+// decodeLines splits text into lines to show which lines differ
+func decodeLines(s string) (any, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	a := make([]any, len(lines))
+	for i := range lines {
+		a[i] = lines[i]
+	}
+	return a, nil
+}
+
+func decodeBase64(decode documentDecoder) documentDecoder {
+	return func(s string) (any, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return decode(string(b))
+	}
+}
+
+// isJsonDocument reports whether s looks like a JSON-encoded object or array
+func isJsonDocument(s string) bool {
+	t := strings.TrimSpace(s)
+	return (strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[")) && json.Valid([]byte(t))
+}
+
+// findDocumentDecoder returns the decoder for the attribute configured in documents,
+// or JSON if both values are JSON-encoded objects or arrays
+func (c Comparer) findDocumentDecoder(path string, oldValue any, newValue any) documentDecoder {
+	for i := range c.documentRules {
+		if c.documentRules[i].path.MatchString(path) {
+			return c.documentRules[i].decode
+		}
+	}
+
+	old, okL := oldValue.(string)
+	new, okR := newValue.(string)
+	if okL && okR && isJsonDocument(old) && isJsonDocument(new) {
+		return decodeJson
+	}
+
+	return nil
+}
+
+func (c Comparer) compareDocument(address string, path string, oldValue any, newValue any, decode documentDecoder) (*ResourceDiff, error) {
+	fmt.Fprintf(c.wDetail, "  compare %s:\n", path)
+	dd := ResourceDiff{Name: path}
+
+	dataL, err := decodeDocument(oldValue, decode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dataR, err := decodeDocument(newValue, decode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dataL = c.inL.normalizeValue(dataL)
+	dataR = c.inR.normalizeValue(dataR)
+
+	patch, err := jsondiff.CompareOpts(dataL, dataR, jsondiff.Equivalent())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range patch {
+		p := patch[i].Path.String()
+
+		if rule := c.ignoredBy(address, path, p, patch[i].OldValue, patch[i].Value); rule != "" {
+			c.suppress(address, path+p, patch[i].OldValue, patch[i].Value, rule)
+			continue
+		}
+
+		old, err := serialize(patch[i].OldValue)
+		if err != nil {
+			return nil, err
+		}
+		new, err := serialize(patch[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(c.wDetail, "    %s : %s -> %s\n", p, old, new)
+		dd.Fields = append(dd.Fields, FieldDiff{Path: p, OldValue: old, NewValue: new})
+	}
+
+	return &dd, nil
+}
+
+func decodeDocument(v any, decode documentDecoder) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("encoded document should be string: %#v", v)
+	}
+	return decode(s)
+}
@@ -0,0 +1,71 @@
+package tfstatediff
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYaml(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want any
+	}{
+		{"empty", "", nil},
+		{"mapping", "a: 1\nb: [x, true]\n", map[string]any{"a": 1.0, "b": []any{"x", true}}},
+		{"non-string keys", "1: a\n", map[string]any{"1": "a"}},
+		{"nested", "a:\n  b:\n    - c: 2\n", map[string]any{"a": map[string]any{"b": []any{map[string]any{"c": 2.0}}}}},
+		{"multiple documents", "kind: A\n---\nkind: B\n", []any{map[string]any{"kind": "A"}, map[string]any{"kind": "B"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeYaml(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := decodeYaml("a: [1"); err == nil {
+		t.Error("no error for invalid YAML")
+	}
+}
+
+func TestDecodeBase64(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		s      string
+		want   any
+	}{
+		{"lines", "base64", encode("#!/bin/sh\necho a\n"), []any{"#!/bin/sh", "echo a"}},
+		{"empty lines", "base64", "", nil},
+		{"json", "base64+json", encode(`{"a":[1]}`), map[string]any{"a": []any{1.0}}},
+		{"yaml", "base64+yaml", encode("a: b\n"), map[string]any{"a": "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := documentDecoders[tt.format](tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := documentDecoders["base64"]("not base64!"); err == nil {
+		t.Error("no error for invalid base64")
+	}
+}
@@ -0,0 +1,121 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wI2L/jsondiff"
+)
+
+// SuppressedDiff is a diff not reported and the rule which suppressed it
+type SuppressedDiff struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	OldValue any    `json:"old_value"`
+	NewValue any    `json:"new_value"`
+	Rule     string `json:"rule"`
+}
+
+type explanation struct {
+	suppressed []SuppressedDiff
+
+	// values before normalization by address
+	rawL map[string]map[string]any
+	rawR map[string]map[string]any
+}
+
+// SetExplain makes the comparison record suppressed diffs in StateDiff.Suppressed
+func (c *Comparer) SetExplain(explain bool) {
+	c.explain = explain
+}
+
+func newExplanation(l []TfResource, r []TfResource) *explanation {
+	e := &explanation{
+		rawL: map[string]map[string]any{},
+		rawR: map[string]map[string]any{},
+	}
+	for i := range l {
+		e.rawL[l[i].Address] = l[i].Values
+	}
+	for j := range r {
+		e.rawR[r[j].Address] = r[j].Values
+	}
+	return e
+}
+
+func (c Comparer) suppress(address string, path string, oldValue any, newValue any, rule string) {
+	if c.ex == nil {
+		return
+	}
+
+	old, err := serialize(oldValue)
+	if err != nil {
+		old = fmt.Sprint(oldValue)
+	}
+	new, err := serialize(newValue)
+	if err != nil {
+		new = fmt.Sprint(newValue)
+	}
+
+	if c.isSensitive(address, path) {
+		old, new = c.maskSensitive(old, new)
+	}
+
+	c.record(address, path, old, new, rule)
+}
+
+// record is suppress for serialized values
+func (c Comparer) record(address string, path string, old string, new string, rule string) {
+	if c.ex == nil {
+		return
+	}
+
+	fmt.Fprintf(c.wDetail, "  (%s) %s : %s -> %s\n", rule, path, old, new)
+	c.ex.suppressed = append(c.ex.suppressed, SuppressedDiff{Name: address, Path: path, OldValue: old, NewValue: new, Rule: rule})
+}
+
+// explainNormalization records diffs between raw values which disappeared by normalization
+func (c Comparer) explainNormalization(l TfResource, r TfResource, s TfSchema, patch jsondiff.Patch) error {
+	if c.ex == nil {
+		return nil
+	}
+
+	rawPatch, err := jsondiff.CompareOpts(c.ex.rawL[l.Address], c.ex.rawR[r.Address], jsondiff.Equivalent())
+	if err != nil {
+		return err
+	}
+
+	paths := map[string]bool{}
+	for k := range patch {
+		paths[patch[k].Path.String()] = true
+	}
+
+	for k := range rawPatch {
+		path := rawPatch[k].Path.String()
+		if paths[path] {
+			continue
+		}
+		rule := "reference normalization"
+		if isInSet(s, path) {
+			rule = "set ordering"
+		} else if strings.HasSuffix(path, "/policy") || strings.HasSuffix(path, "/inline_policy") || strings.HasSuffix(path, "/assume_role_policy") {
+			rule = "policy normalization"
+		}
+		c.suppress(l.Address, path, rawPatch[k].OldValue, rawPatch[k].Value, rule)
+	}
+
+	return nil
+}
+
+// isInSet reports whether the path points into a set
+func isInSet(s TfSchema, path string) bool {
+	for i := 1; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		if set, err := isSet(s, path[:i]); err == nil && set {
+			return true
+		}
+	}
+	return false
+}
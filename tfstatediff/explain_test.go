@@ -0,0 +1,81 @@
+package tfstatediff
+
+import (
+	"testing"
+)
+
+func TestExplainNormalization(t *testing.T) {
+	schema := TfProvidersSchema{
+		ProviderSchema: map[string]TfProviderSchema{
+			testProvider: {
+				ResourceSchemas: map[string]TfSchema{
+					"aws_vpc": {Block: TfSchemaBlock{Attributes: map[string]TfSchemaAttribute{
+						"id": {Type: "string", Computed: true},
+					}}},
+					"aws_iam_policy": {Block: TfSchemaBlock{Attributes: map[string]TfSchemaAttribute{
+						"id":      {Type: "string", Computed: true},
+						"vpc_id":  {Type: "string", Optional: true},
+						"vpc_ids": {Type: []any{"set", "string"}, Optional: true},
+						"policy":  {Type: "string", Required: true},
+						"name":    {Type: "string", Required: true},
+						"tags":    {Type: []any{"map", "string"}, Optional: true},
+					}}},
+				},
+			},
+		},
+	}
+
+	values := func(env string, vpcId string, vpcIds []any, policy string) TfValues {
+		return TfValues{RootModule: TfModule{Resources: []TfResource{
+			{Address: "aws_vpc.a", Mode: "managed", Type: "aws_vpc", Name: "a", ProviderName: testProvider, Values: map[string]any{"id": vpcId}},
+			{Address: "aws_iam_policy.p", Mode: "managed", Type: "aws_iam_policy", Name: "p", ProviderName: testProvider, Values: map[string]any{
+				"id":      "p",
+				"vpc_id":  vpcId,
+				"vpc_ids": vpcIds,
+				"policy":  policy,
+				"name":    env + "-p",
+				"tags":    map[string]any{"Env": env},
+			}},
+		}}}
+	}
+
+	c, err := New(Config{IgnoreDiff: []ConfigIgnoreDiff{{Left: "stg", Right: "prod"}}}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetExplain(true)
+
+	d, err := c.compareValues(
+		// sets equal except for order are not diffs even before normalization, unlike sets also with references
+		values("stg", "vpc-1", []any{"vpc-1", "vpc-0"}, `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject"],"Resource":"*"}]}`),
+		values("prod", "vpc-2", []any{"vpc-0", "vpc-2"}, `{"Statement": [{"Resource":"*","Effect":"Allow","Action":["s3:PutObject","s3:GetObject"]}]}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Diffs) != 0 {
+		t.Errorf("diffs: %+v", d.Diffs)
+	}
+
+	got := map[string]string{}
+	for _, s := range d.Suppressed {
+		got[s.Name+" "+s.Path] = s.Rule
+	}
+	want := map[string]string{
+		"aws_vpc.a /id":               "reference normalization",
+		"aws_iam_policy.p /vpc_id":    "reference normalization",
+		"aws_iam_policy.p /vpc_ids/0": "set ordering",
+		"aws_iam_policy.p /vpc_ids/1": "set ordering",
+		"aws_iam_policy.p /policy":    "policy normalization",
+		"aws_iam_policy.p /name":      `ignore_diff ("stg" = "prod")`,
+		"aws_iam_policy.p /tags/Env":  "ignore_tags",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("suppressed: %v", got)
+	}
+}
@@ -0,0 +1,305 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+type idNormalizer struct {
+	address_by_id map[string]string
+
+	// for providers whose references are case-insensitive, keyed by lowercase
+	address_by_folded_id map[string]string
+
+	wWarn io.Writer
+}
+
+func newIdNormalizer(rs []TfResource, sources []idSource, wWarn io.Writer) idNormalizer {
+	n := idNormalizer{
+		address_by_id:        map[string]string{},
+		address_by_folded_id: map[string]string{},
+		wWarn:                wWarn,
+	}
+	n.collectReferences(rs)
+	n.collectAddresses(rs, sources)
+	return n
+}
+
+func (n idNormalizer) normalize(val map[string]any) map[string]any {
+	return replaceJsonMap("", val, n.replace, n.wWarn)
+}
+
+func (n idNormalizer) replace(key string, value string) string {
+	normalized := value
+	if a, ok := n.address_by_id[value]; ok {
+		normalized = a
+	} else if a, ok := n.address_by_folded_id[strings.ToLower(value)]; ok {
+		normalized = a
+	}
+	return normalized
+}
+
+func (n idNormalizer) normalizeValue(val any) any {
+	switch v := val.(type) {
+	case map[string]any:
+		return n.normalize(v)
+	case []any:
+		return replaceJsonSlice("", v, n.replace, n.wWarn)
+	case string:
+		return n.replace("", v)
+	}
+	return val
+}
+
+// referenceProfile describes how resources of a provider are referenced by others
+type referenceProfile struct {
+	// attributes holding globally unique references, such as ARNs
+	attributes      []string
+	caseInsensitive bool
+}
+
+var referenceProfiles = map[string]referenceProfile{
+	"aws": {
+		attributes: []string{"arn", "iam_arn"},
+	},
+	"google": {
+		// e.g. https://www.googleapis.com/compute/v1/projects/p/global/networks/n and projects/p/global/networks/n
+		attributes: []string{"self_link", "id"},
+	},
+	"google-beta": {
+		attributes: []string{"self_link", "id"},
+	},
+	"azurerm": {
+		// e.g. /subscriptions/.../resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet
+		attributes:      []string{"id"},
+		caseInsensitive: true,
+	},
+}
+
+// findReferenceProfile selects a profile by the type of provider (e.g. registry.terraform.io/hashicorp/aws).
+// Unknown providers are treated as AWS.
+func findReferenceProfile(providerName string) referenceProfile {
+	t := providerName[strings.LastIndex(providerName, "/")+1:]
+	if p, ok := referenceProfiles[t]; ok {
+		return p
+	}
+	return referenceProfiles["aws"]
+}
+
+func (n idNormalizer) collectReferences(rs []TfResource) {
+	for i := range rs {
+		r := rs[i]
+		p := findReferenceProfile(r.ProviderName)
+		for _, attr := range p.attributes {
+			if val, ok := r.Values[attr]; ok {
+				if ref, ok := val.(string); ok {
+					if ref == "" {
+						fmt.Fprintf(n.wWarn, "[warn] %s.%s is empty\n", r.Address, attr)
+						continue
+					}
+					if p.caseInsensitive {
+						n.address_by_folded_id[strings.ToLower(ref)] = addressNormalize(r.Address)
+					} else {
+						n.address_by_id[ref] = addressNormalize(r.Address)
+					}
+				} else {
+					fmt.Fprintf(n.wWarn, "[warn] %s.%s should be string\n", r.Address, attr)
+				}
+			}
+		}
+	}
+}
+
+type idSource struct {
+	resourceType string
+	typePattern  *regexp.Regexp
+	idAttributes []string
+	list         bool
+}
+
+func (s idSource) matches(r TfResource) bool {
+	if s.typePattern != nil {
+		return s.typePattern.MatchString(r.Type)
+	}
+	return s.resourceType == r.Type
+}
+
+var defaultIdSources = []idSource{
+	{
+		resourceType: "aws_subnet",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_security_group",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_efs_file_system",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_vpc",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_vpc_endpoint",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_service_discovery_private_dns_namespace",
+		idAttributes: []string{"id"},
+	},
+	{
+		resourceType: "aws_kms_key",
+		idAttributes: []string{"key_id"},
+	},
+	{
+		resourceType: "aws_route_table",
+		idAttributes: []string{"id"},
+	},
+}
+
+// newIdSources returns the built-in sources followed by the configured ones
+func newIdSources(c []ConfigIdSource) ([]idSource, error) {
+	sources := append([]idSource{}, defaultIdSources...)
+
+	for i := range c {
+		if (c[i].Type == "") == (c[i].TypePattern == "") {
+			return nil, fmt.Errorf("id_sources[%d]: either type or type_pattern is required", i)
+		}
+		if len(c[i].Attributes) == 0 {
+			return nil, fmt.Errorf("id_sources[%d]: attributes are required", i)
+		}
+
+		s := idSource{
+			resourceType: c[i].Type,
+			idAttributes: c[i].Attributes,
+			list:         c[i].List,
+		}
+		if c[i].TypePattern != "" {
+			// match the whole type
+			re, err := regexp.Compile("^(?:" + c[i].TypePattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("id_sources[%d]: %w", i, err)
+			}
+			s.typePattern = re
+		}
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+func (n idNormalizer) collectAddresses(rs []TfResource, sources []idSource) {
+	for i := range rs {
+		r := rs[i]
+		for j := range sources {
+			if !sources[j].matches(r) {
+				continue
+			}
+			for _, attr := range sources[j].idAttributes {
+				val, ok := r.Values[attr]
+				if !ok {
+					continue
+				}
+				// IDs in a list share the address, since their order may differ between the sides
+				address := fmt.Sprintf("%s.%s", addressNormalize(r.Address), attr)
+				if sources[j].list {
+					ids, ok := val.([]any)
+					if !ok {
+						fmt.Fprintf(n.wWarn, "[warn] %s.%s should be list\n", r.Address, attr)
+						continue
+					}
+					for k := range ids {
+						if id, ok := ids[k].(string); ok {
+							n.addAddress(id, address)
+						} else {
+							fmt.Fprintf(n.wWarn, "[warn] %s.%s.%d should be string\n", r.Address, attr, k)
+						}
+					}
+				} else if id, ok := val.(string); ok {
+					n.addAddress(id, address)
+				} else {
+					fmt.Fprintf(n.wWarn, "[warn] %s.%s should be string\n", r.Address, attr)
+				}
+			}
+		}
+	}
+}
+
+// addAddress maps the ID to the address unless it is already mapped, so that built-in sources take precedence
+func (n idNormalizer) addAddress(id string, address string) {
+	if a, ok := n.address_by_id[id]; ok {
+		if a != address {
+			fmt.Fprintf(n.wWarn, "[warn] %s is already mapped to %s, not to %s\n", id, a, address)
+		}
+		return
+	}
+	n.address_by_id[id] = address
+}
+
+type jsonStringReplacer func(key string, value string) string
+
+func replaceJsonMap(prefix string, d map[string]any, fn jsonStringReplacer, wWarn io.Writer) map[string]any {
+	res := map[string]any{}
+
+	for i := range d {
+		var p string
+		if prefix == "" {
+			p = i
+		} else {
+			p = fmt.Sprintf("%s.%s", prefix, i)
+		}
+		if val, ok := d[i].(string); ok {
+			res[i] = fn(p, val)
+		} else if val, ok := d[i].(map[string]any); ok {
+			res[i] = replaceJsonMap(p, val, fn, wWarn)
+		} else if val, ok := d[i].([]any); ok {
+			res[i] = replaceJsonSlice(p, val, fn, wWarn)
+		} else {
+			res[i] = replaceJsonScalar(p, d[i], fn, wWarn)
+		}
+	}
+
+	return res
+}
+
+func replaceJsonSlice(prefix string, s []any, fn jsonStringReplacer, wWarn io.Writer) []any {
+	res := make([]any, len(s))
+
+	for i := range s {
+		var p string
+		if prefix == "" {
+			p = fmt.Sprintf("%d", i)
+		} else {
+			p = fmt.Sprintf("%s.%d", prefix, i)
+		}
+		if val, ok := s[i].(string); ok {
+			res[i] = fn(p, val)
+		} else if val, ok := s[i].(map[string]any); ok {
+			res[i] = replaceJsonMap(p, val, fn, wWarn)
+		} else if val, ok := s[i].([]any); ok {
+			res[i] = replaceJsonSlice(p, val, fn, wWarn)
+		} else {
+			res[i] = replaceJsonScalar(p, s[i], fn, wWarn)
+		}
+	}
+
+	return res
+}
+
+func replaceJsonScalar(prefix string, v any, fn jsonStringReplacer, wWarn io.Writer) any {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil
+	}
+	k := t.Kind()
+	if k == reflect.Int || k == reflect.Float64 || k == reflect.Bool {
+		return v
+	}
+	fmt.Fprintf(wWarn, "[warn] unknown type: %s %#v\n", prefix, v)
+	return v
+}
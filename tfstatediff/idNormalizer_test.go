@@ -0,0 +1,157 @@
+package tfstatediff
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIdNormalizerKeepsScalars(t *testing.T) {
+	n := newIdNormalizer(nil, nil, io.Discard)
+
+	values := map[string]any{
+		"count":   2.0,
+		"enabled": false,
+		"none":    nil,
+		"list":    []any{1.0, true, nil},
+		"nested":  map[string]any{"port": 443.0},
+	}
+
+	if got := n.normalize(values); !reflect.DeepEqual(got, values) {
+		t.Errorf("got %#v, want %#v", got, values)
+	}
+}
+
+func TestCollectAddresses(t *testing.T) {
+	sources, err := newIdSources([]ConfigIdSource{
+		{TypePattern: "aws_.+_subnet_group", Attributes: []string{"subnet_ids"}, List: true},
+		{Type: "aws_vpc_endpoint", Attributes: []string{"network_interface_ids"}, List: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := []TfResource{
+		{Address: "aws_subnet.a", Type: "aws_subnet", Values: map[string]any{"id": "subnet-1"}},
+		{Address: "aws_db_subnet_group.g", Type: "aws_db_subnet_group", Values: map[string]any{"subnet_ids": []any{"subnet-2", "subnet-1"}}},
+		{Address: "aws_vpc_endpoint.e", Type: "aws_vpc_endpoint", Values: map[string]any{"network_interface_ids": []any{"eni-2", "eni-1"}}},
+	}
+	var w bytes.Buffer
+	n := newIdNormalizer(rs, sources, &w)
+
+	want := map[string]string{
+		// built-in sources take precedence
+		"subnet-1": "aws_subnet.a.id",
+		"subnet-2": "aws_db_subnet_group.g.subnet_ids",
+		// independent of the order of the list
+		"eni-1": "aws_vpc_endpoint.e.network_interface_ids",
+		"eni-2": "aws_vpc_endpoint.e.network_interface_ids",
+	}
+	if !reflect.DeepEqual(n.address_by_id, want) {
+		t.Errorf("got %v, want %v", n.address_by_id, want)
+	}
+	if !strings.Contains(w.String(), "[warn] subnet-1 is already mapped to aws_subnet.a.id") {
+		t.Errorf("warnings: %q", w.String())
+	}
+}
+
+func TestNewIdSources(t *testing.T) {
+	sources, err := newIdSources([]ConfigIdSource{
+		{Type: "aws_lb_target_group", Attributes: []string{"arn_suffix"}},
+		{TypePattern: "aws_(lb|alb)", Attributes: []string{"dns_name"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != len(defaultIdSources)+2 {
+		t.Fatalf("sources: %+v", sources)
+	}
+
+	tests := []struct {
+		resourceType string
+		want         bool
+	}{
+		{"aws_lb", true},
+		{"aws_alb", true},
+		// type_pattern matches the whole type
+		{"aws_lb_listener", false},
+		{"aws_lb_target_group", true},
+	}
+	for _, tt := range tests {
+		got := false
+		for _, s := range sources[len(defaultIdSources):] {
+			got = got || s.matches(TfResource{Type: tt.resourceType})
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.resourceType, got, tt.want)
+		}
+	}
+
+	for _, c := range []ConfigIdSource{
+		{Attributes: []string{"id"}},
+		{Type: "a", TypePattern: "a", Attributes: []string{"id"}},
+		{Type: "a"},
+		{TypePattern: "(", Attributes: []string{"id"}},
+	} {
+		if _, err := newIdSources([]ConfigIdSource{c}); err == nil {
+			t.Errorf("%+v: no error", c)
+		}
+	}
+}
+
+func TestFindReferenceProfile(t *testing.T) {
+	tests := []struct {
+		providerName    string
+		attributes      []string
+		caseInsensitive bool
+	}{
+		{"registry.terraform.io/hashicorp/aws", []string{"arn", "iam_arn"}, false},
+		{"registry.terraform.io/hashicorp/google", []string{"self_link", "id"}, false},
+		{"registry.terraform.io/hashicorp/google-beta", []string{"self_link", "id"}, false},
+		{"registry.terraform.io/hashicorp/azurerm", []string{"id"}, true},
+		// unknown providers are treated as AWS
+		{"registry.terraform.io/example/unknown", []string{"arn", "iam_arn"}, false},
+		{"aws", []string{"arn", "iam_arn"}, false},
+	}
+
+	for _, tt := range tests {
+		p := findReferenceProfile(tt.providerName)
+		if !reflect.DeepEqual(p.attributes, tt.attributes) || p.caseInsensitive != tt.caseInsensitive {
+			t.Errorf("%s: got %+v", tt.providerName, p)
+		}
+	}
+}
+
+func TestIdNormalizerReferenceProfiles(t *testing.T) {
+	rs := []TfResource{
+		{Address: "google_compute_network.n", ProviderName: "registry.terraform.io/hashicorp/google", Values: map[string]any{
+			"id":        "projects/p/global/networks/n",
+			"self_link": "https://www.googleapis.com/compute/v1/projects/p/global/networks/n",
+		}},
+		{Address: "azurerm_virtual_network.v", ProviderName: "registry.terraform.io/hashicorp/azurerm", Values: map[string]any{
+			"id": "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet",
+		}},
+	}
+	n := newIdNormalizer(rs, nil, io.Discard)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"projects/p/global/networks/n", "google_compute_network.n"},
+		{"https://www.googleapis.com/compute/v1/projects/p/global/networks/n", "google_compute_network.n"},
+		// google references are case-sensitive
+		{"projects/p/global/networks/N", "projects/p/global/networks/N"},
+		{"/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet", "azurerm_virtual_network.v"},
+		// azurerm references are case-insensitive
+		{"/subscriptions/s/resourcegroups/RG/providers/microsoft.network/virtualnetworks/vnet", "azurerm_virtual_network.v"},
+	}
+
+	for _, tt := range tests {
+		if got := n.normalizeValue(tt.value); got != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type ignoreDiffRule struct {
+	// where the rule is defined, e.g. ignore_diff[0]
+	name  string
+	left  string
+	right string
+	hits  *int
+
+	// for regexp rules, left is matched by pattern and right is expanded from it
+	pattern  *regexp.Regexp
+	anywhere *regexp.Regexp
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_]\w*)\}`)
+
+func newIgnoreDiffRules(c []ConfigIgnoreDiff, placeholders map[string]ConfigPlaceholder) ([]ignoreDiffRule, error) {
+	rules := []ignoreDiffRule{}
+
+	names := []string{}
+	for name := range placeholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// placeholders are only substituted in ignore_diff, not ignored by themselves
+	for _, name := range names {
+		p := placeholders[name]
+		if p.Left == "" && p.Right == "" {
+			return nil, fmt.Errorf("placeholders.%s: left or right is required", name)
+		}
+	}
+
+	for i := range c {
+		if !c[i].Regexp {
+			left, err := expandPlaceholders(c[i].Left, placeholders, leftOf, noEscape)
+			if err != nil {
+				return nil, fmt.Errorf("ignore_diff[%d]: %w", i, err)
+			}
+			right, err := expandPlaceholders(c[i].Right, placeholders, rightOf, noEscape)
+			if err != nil {
+				return nil, fmt.Errorf("ignore_diff[%d]: %w", i, err)
+			}
+			if left == "" && right == "" {
+				return nil, fmt.Errorf("ignore_diff[%d]: left or right is required", i)
+			}
+			rules = append(rules, ignoreDiffRule{name: fmt.Sprintf("ignore_diff[%d]", i), left: left, right: right, hits: new(int)})
+			continue
+		}
+
+		left, err := expandPlaceholders(c[i].Left, placeholders, leftOf, regexp.QuoteMeta)
+		if err != nil {
+			return nil, fmt.Errorf("ignore_diff[%d]: %w", i, err)
+		}
+		right, err := expandPlaceholders(c[i].Right, placeholders, rightOf, escapeTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("ignore_diff[%d]: %w", i, err)
+		}
+		pattern, anywhere, err := compileIgnoreDiff(left)
+		if err != nil {
+			return nil, fmt.Errorf("ignore_diff[%d]: %w", i, err)
+		}
+		rules = append(rules, ignoreDiffRule{
+			name:     fmt.Sprintf("ignore_diff[%d]", i),
+			hits:     new(int),
+			left:     c[i].Left,
+			right:    right,
+			pattern:  pattern,
+			anywhere: anywhere,
+		})
+	}
+
+	return rules, nil
+}
+
+// expandPlaceholders replaces {name} in s with the escaped value of the side of the placeholder
+func expandPlaceholders(s string, placeholders map[string]ConfigPlaceholder, side func(ConfigPlaceholder) string, escape func(string) string) (string, error) {
+	var err error
+	expanded := placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		p, ok := placeholders[m[1:len(m)-1]]
+		if !ok {
+			err = fmt.Errorf("unknown placeholder: %s", m)
+			return m
+		}
+		return escape(side(p))
+	})
+	return expanded, err
+}
+
+func leftOf(p ConfigPlaceholder) string {
+	return p.Left
+}
+
+func rightOf(p ConfigPlaceholder) string {
+	return p.Right
+}
+
+// compileIgnoreDiff compiles the left of a regexp rule matched at the heads of values, and anywhere for rewrite
+func compileIgnoreDiff(left string) (*regexp.Regexp, *regexp.Regexp, error) {
+	pattern, err := regexp.Compile("^(?:" + left + ")")
+	if err != nil {
+		return nil, nil, err
+	}
+	anywhere, err := regexp.Compile(left)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pattern, anywhere, nil
+}
+
+func noEscape(s string) string {
+	return s
+}
+
+func escapeTemplate(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func (r ignoreDiffRule) String() string {
+	if r.pattern != nil {
+		return fmt.Sprintf("%s => %s", r.left, r.right)
+	}
+	return fmt.Sprintf("%q = %q", r.left, r.right)
+}
+
+// match returns the lengths consumed from the heads of old and new if the rule applies
+func (r ignoreDiffRule) match(old string, new string) (int, int, bool) {
+	if r.pattern == nil {
+		if strings.HasPrefix(old, r.left) && strings.HasPrefix(new, r.right) {
+			return len(r.left), len(r.right), true
+		}
+		return 0, 0, false
+	}
+
+	m := r.pattern.FindStringSubmatchIndex(old)
+	if m == nil {
+		return 0, 0, false
+	}
+	expanded := string(r.pattern.ExpandString(nil, r.right, old, m))
+	if !strings.HasPrefix(new, expanded) {
+		return 0, 0, false
+	}
+	return m[1], len(expanded), true
+}
+
+// rewrite replaces the left of the rule with the right in s, or the longer one with the shorter one for literal rules,
+// so that values equal by the rule are sorted in the same order.
+// It is not counted as a hit, since the sorted values are still compared by ignoredBy.
+func (r ignoreDiffRule) rewrite(s string) string {
+	if r.pattern == nil {
+		if len(r.left) >= len(r.right) {
+			return strings.ReplaceAll(s, r.left, r.right)
+		}
+		return strings.ReplaceAll(s, r.right, r.left)
+	}
+	return r.anywhere.ReplaceAllString(s, r.right)
+}
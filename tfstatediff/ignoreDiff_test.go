@@ -0,0 +1,102 @@
+package tfstatediff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewIgnoreDiffRulesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{
+			name:   "regexp valid only when wrapped",
+			config: Config{IgnoreDiff: []ConfigIgnoreDiff{{Left: "a)|(?:b", Right: "c", Regexp: true}}},
+			want:   "ignore_diff[0]: error parsing regexp",
+		},
+		{
+			name:   "unknown placeholder",
+			config: Config{IgnoreDiff: []ConfigIgnoreDiff{{Left: "{env}", Right: "{env}"}}},
+			want:   "ignore_diff[0]: unknown placeholder: {env}",
+		},
+		{
+			name:   "empty placeholder",
+			config: Config{Placeholders: map[string]ConfigPlaceholder{"env": {}}},
+			want:   "placeholders.env: left or right is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config, TfProvidersSchema{})
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaceholdersOnlySubstituted(t *testing.T) {
+	placeholders := map[string]ConfigPlaceholder{"env": {Left: "stg", Right: "prod"}}
+
+	c, err := New(Config{Placeholders: placeholders, IgnoreDiff: []ConfigIgnoreDiff{{Left: "role/{env}-", Right: "role/{env}-"}}}, TfProvidersSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := c.ignoredBy("aws_instance.a", "", "/name", "stg", "prod"); rule != "" {
+		t.Errorf("bare placeholder ignored by %s", rule)
+	}
+	if rule := c.ignoredBy("aws_instance.a", "", "/name", "role/stg-a", "role/prod-a"); rule == "" {
+		t.Error("substituted rule not applied")
+	}
+
+	c, err = New(Config{Placeholders: placeholders, IgnoreDiff: []ConfigIgnoreDiff{{Left: "{env}", Right: "{env}"}}}, TfProvidersSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := c.ignoredBy("aws_instance.a", "", "/name", "stg", "prod"); rule == "" {
+		t.Error("bare pair not ignored")
+	}
+}
+
+func TestIgnoreDiffRuleMatch(t *testing.T) {
+	placeholders := map[string]ConfigPlaceholder{"account": {Left: "111", Right: "222"}, "dot": {Left: "a.b", Right: "a$b"}}
+
+	tests := []struct {
+		name   string
+		rule   ConfigIgnoreDiff
+		old    string
+		new    string
+		wantOk bool
+		// lengths consumed from old and new
+		wantI int
+		wantJ int
+	}{
+		{"literal", ConfigIgnoreDiff{Left: "stg", Right: "prod"}, "stg-a", "prod-a", true, 3, 4},
+		{"literal at heads only", ConfigIgnoreDiff{Left: "stg", Right: "prod"}, "a-stg", "a-prod", false, 0, 0},
+		{"literal empty left", ConfigIgnoreDiff{Left: "", Right: "prod-"}, "a", "prod-a", true, 0, 5},
+		{"literal placeholder", ConfigIgnoreDiff{Left: "{account}:role/", Right: "{account}:role/"}, "111:role/a", "222:role/a", true, 9, 9},
+		{"regexp", ConfigIgnoreDiff{Left: `stg-(\d+)`, Right: "prod-$1", Regexp: true}, "stg-12-a", "prod-12-a", true, 6, 7},
+		{"regexp different groups", ConfigIgnoreDiff{Left: `stg-(\d+)`, Right: "prod-$1", Regexp: true}, "stg-12", "prod-13", false, 0, 0},
+		{"regexp at heads only", ConfigIgnoreDiff{Left: `stg`, Right: "prod", Regexp: true}, "a-stg", "prod", false, 0, 0},
+		{"regexp alternation", ConfigIgnoreDiff{Left: `a|b`, Right: "c", Regexp: true}, "b", "c", true, 1, 1},
+		// placeholders are literal in both the regexp and the replacement
+		{"regexp placeholder", ConfigIgnoreDiff{Left: `{dot}-(\w+)`, Right: "{dot}-$1", Regexp: true}, "a.b-x", "a$b-x", true, 5, 5},
+		{"regexp placeholder not a pattern", ConfigIgnoreDiff{Left: `{dot}`, Right: "{dot}", Regexp: true}, "acb", "a$b", false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newIgnoreDiffRules([]ConfigIgnoreDiff{tt.rule}, placeholders)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i, j, ok := rules[0].match(tt.old, tt.new)
+			if ok != tt.wantOk || i != tt.wantI || j != tt.wantJ {
+				t.Errorf("got (%d, %d, %v), want (%d, %d, %v)", i, j, ok, tt.wantI, tt.wantJ, tt.wantOk)
+			}
+		})
+	}
+}
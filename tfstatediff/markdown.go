@@ -0,0 +1,130 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PrintMarkdown writes the result in Markdown suitable for pull request comments and step summaries
+func (cr ComparisonResult) PrintMarkdown(w io.Writer) {
+	d, p := cr.StateDiff, cr.PlanDiff
+
+	fmt.Fprintln(w, "## tfstate-diff")
+	fmt.Fprintln(w, "")
+
+	if p != nil {
+		fmt.Fprintf(w, "- common resources: %d (%s)\n", p.Common, delta(p.Common-d.Common))
+		fmt.Fprintf(w, "- resources with diff: %d (%s)\n", len(p.Diffs), delta(len(p.Diffs)-len(d.Diffs)))
+		fmt.Fprintf(w, "- left only resources: %d (%s)\n", len(p.LeftOnly), delta(len(p.LeftOnly)-len(d.LeftOnly)))
+		fmt.Fprintf(w, "- right only resources: %d (%s)\n", len(p.RightOnly), delta(len(p.RightOnly)-len(d.RightOnly)))
+		fmt.Fprintf(w, "- outputs with diff: %d (%s)\n", len(p.OutputDiffs), delta(len(p.OutputDiffs)-len(d.OutputDiffs)))
+	} else {
+		fmt.Fprintf(w, "- common resources: %d\n", d.Common)
+		fmt.Fprintf(w, "- resources with diff: %d\n", len(d.Diffs))
+		fmt.Fprintf(w, "- left only resources: %d\n", len(d.LeftOnly))
+		fmt.Fprintf(w, "- right only resources: %d\n", len(d.RightOnly))
+		fmt.Fprintf(w, "- outputs with diff: %d\n", len(d.OutputDiffs))
+	}
+
+	if p != nil {
+		printMarkdownDiff(w, "Plan", p)
+	}
+	printMarkdownDiff(w, "State", d)
+}
+
+func printMarkdownDiff(w io.Writer, title string, d *StateDiff) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "## %s diffs\n", title)
+
+	printMarkdownDetails(w, "Resources with diff", func() {
+		for _, rd := range d.Diffs {
+			fmt.Fprintf(w, "- %s\n", rd.Name)
+			printMarkdownFields(w, "  ", rd.Fields)
+			for _, pd := range rd.Policies {
+				fmt.Fprintf(w, "  - %s\n", pd.Name)
+				printMarkdownFields(w, "    ", pd.Fields)
+				for _, sd := range pd.Policies {
+					fmt.Fprintf(w, "    - %s (%s)\n", sd.Name, sd.Change)
+					printMarkdownFields(w, "      ", sd.Fields)
+				}
+			}
+			for _, dd := range rd.Documents {
+				fmt.Fprintf(w, "  - %s\n", dd.Name)
+				printMarkdownFields(w, "    ", dd.Fields)
+			}
+		}
+	})
+
+	if len(d.OutputDiffs) > 0 {
+		printMarkdownDetails(w, "Outputs with diff", func() {
+			for _, od := range d.OutputDiffs {
+				fmt.Fprintf(w, "- %s (%s)\n", od.Name, od.Change)
+				printMarkdownFields(w, "  ", od.Fields)
+			}
+		})
+	}
+
+	printMarkdownDetails(w, "Left-only resources", func() {
+		for _, a := range d.LeftOnly {
+			fmt.Fprintf(w, "- %s\n", a)
+		}
+	})
+
+	printMarkdownDetails(w, "Right-only resources", func() {
+		for _, a := range d.RightOnly {
+			fmt.Fprintf(w, "- %s\n", a)
+		}
+	})
+
+	if len(d.Errors) > 0 {
+		printMarkdownDetails(w, "Errors", func() {
+			for _, e := range d.Errors {
+				fmt.Fprintf(w, "- %s : %s\n", e.Address, codeSpan(e.Message))
+			}
+		})
+	}
+}
+
+func printMarkdownDetails(w io.Writer, title string, body func()) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "#### %s\n", title)
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "<details>")
+	fmt.Fprintln(w, "<summary>Results</summary>")
+	fmt.Fprintln(w, "")
+	body()
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "</details>")
+}
+
+func printMarkdownFields(w io.Writer, indent string, fields []FieldDiff) {
+	for _, f := range fields {
+		if f.Path == "" {
+			// whole value, e.g. an added statement
+			fmt.Fprintf(w, "%s- %s -> %s\n", indent, codeSpan(fmt.Sprint(f.OldValue)), codeSpan(fmt.Sprint(f.NewValue)))
+			continue
+		}
+		fmt.Fprintf(w, "%s- %s : %s -> %s\n", indent, f.Path, codeSpan(fmt.Sprint(f.OldValue)), codeSpan(fmt.Sprint(f.NewValue)))
+	}
+}
+
+// delta formats a difference of counts as +1, ±0 or -1
+func delta(n int) string {
+	if n == 0 {
+		return "±0"
+	}
+	return fmt.Sprintf("%+d", n)
+}
+
+// codeSpan wraps s in backticks, using a longer fence than any run of backticks in s
+func codeSpan(s string) string {
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
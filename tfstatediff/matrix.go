@@ -0,0 +1,302 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/koron/go-dproxy"
+	"github.com/wI2L/jsondiff"
+)
+
+type LabeledState struct {
+	Label string
+	State *TfStatePlan
+}
+
+// MatrixResult is the comparison of resources across environments
+type MatrixResult struct {
+	Labels    []string         `json:"labels"`
+	Resources []MatrixResource `json:"resources"`
+	Errors    []ResourceError  `json:"errors,omitempty"`
+}
+
+type MatrixResource struct {
+	Address string `json:"address"`
+	// labels of the environments which have the resource
+	Environments []string      `json:"environments"`
+	Fields       []MatrixField `json:"fields,omitempty"`
+}
+
+type MatrixField struct {
+	Path string `json:"path"`
+	// by label; absent if the environment does not have the resource or the value
+	Values map[string]any `json:"values"`
+}
+
+func (s TfStatePlan) plannedValues() TfValues {
+	if s.PlannedValues != nil {
+		return *s.PlannedValues
+	}
+	return orEmpty(s.Values)
+}
+
+type matrixEntry struct {
+	env      int
+	resource TfResource
+	err      error
+}
+
+// CompareMatrix compares resources of N environments, planned values for plans.
+// The first state is regarded as the left of address_map and ignore_diff, and the others as the right.
+func (c Comparer) CompareMatrix(states []LabeledState) (*MatrixResult, error) {
+	if len(states) < 2 {
+		return nil, fmt.Errorf("at least 2 states are required")
+	}
+
+	result := MatrixResult{Labels: []string{}, Resources: []MatrixResource{}, Errors: []ResourceError{}}
+
+	keys := []string{}
+	entries := map[string][]matrixEntry{}
+
+	for i := range states {
+		result.Labels = append(result.Labels, states[i].Label)
+
+		rs := states[i].State.plannedValues().RootModule.resources()
+		in, sn, mapped := newIdNormalizer(rs, c.idSources, c.wDetail), c.snR, rs
+		if i == 0 {
+			mapped = c.am.mapResources(rs)
+			in, sn = newIdNormalizer(mapped, c.idSources, c.wDetail), c.snL
+		}
+
+		normalized, errs, err := c.normalizeResources(in, sn, rs)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := range normalized {
+			key := addressNormalize(mapped[j].Address)
+			if _, ok := entries[key]; !ok {
+				keys = append(keys, key)
+			}
+			entries[key] = append(entries[key], matrixEntry{env: i, resource: normalized[j], err: errs[rs[j].Address]})
+		}
+	}
+
+	for _, key := range keys {
+		es := entries[key]
+		mr := MatrixResource{Address: es[0].resource.Address, Environments: []string{}}
+		for _, e := range es {
+			mr.Environments = append(mr.Environments, states[e.env].Label)
+		}
+
+		fmt.Fprintf(c.wDetail, "compare %s [%s]\n", mr.Address, strings.Join(mr.Environments, " "))
+
+		fields, err := c.compareMatrixEntries(states, es)
+		if err != nil {
+			if !c.keepGoing {
+				return nil, err
+			}
+			fmt.Fprintf(c.wDetail, "  error: %s\n", err)
+			result.Errors = append(result.Errors, ResourceError{Address: mr.Address, Message: err.Error()})
+		}
+		mr.Fields = fields
+
+		for _, f := range mr.Fields {
+			fmt.Fprintf(c.wDetail, "  %s\n", f.Path)
+			for _, label := range mr.Environments {
+				if v, ok := f.Values[label]; ok {
+					fmt.Fprintf(c.wDetail, "    %s: %s\n", label, serializeOrRaw(v))
+				}
+			}
+		}
+
+		result.Resources = append(result.Resources, mr)
+	}
+
+	return &result, nil
+}
+
+// compareMatrixEntries compares each environment with the first one having the resource
+// and returns the values of the differing paths in all environments
+func (c Comparer) compareMatrixEntries(states []LabeledState, es []matrixEntry) ([]MatrixField, error) {
+	for _, e := range es {
+		if e.err != nil {
+			return nil, e.err
+		}
+	}
+
+	ref := es[0].resource
+	sRef, err := c.matrixNormalizer(es[0].env).findSchema(ref)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref.Address, err)
+	}
+
+	paths := []string{}
+	found := map[string]bool{}
+
+	for _, e := range es[1:] {
+		s, err := c.matrixNormalizer(e.env).findSchema(e.resource)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.resource.Address, err)
+		}
+
+		patch, err := jsondiff.CompareOpts(ref.Values, e.resource.Values, jsondiff.Equivalent())
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range patch {
+			path := patch[k].Path.String()
+			if found[path] || c.ignoredBy(ref.Address, "", path, patch[k].OldValue, patch[k].Value) != "" {
+				continue
+			}
+			isArg, err := isArgument(sRef, path[1:])
+			if err != nil {
+				isArg, err = isArgument(s, path[1:])
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ref.Address, err)
+			}
+			if !isArg && c.ignoreComputed {
+				continue
+			}
+			found[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	fields := []MatrixField{}
+	for _, path := range paths {
+		sensitive := false
+		for _, e := range es {
+			sensitive = sensitive || (!c.showSensitive && isSensitivePath(e.resource.SensitiveValues, path))
+		}
+
+		f := MatrixField{Path: path, Values: map[string]any{}}
+		var refValue *string
+		for _, e := range es {
+			v, err := dproxy.Pointer(e.resource.Values, path).Value()
+			if err != nil {
+				continue
+			}
+			if !sensitive {
+				f.Values[states[e.env].Label] = v
+				continue
+			}
+
+			// sensitive values are compared with the first environment having the value
+			s := serializeOrRaw(v)
+			if refValue == nil {
+				refValue = &s
+				f.Values[states[e.env].Label] = maskedValue(sensitiveMask)
+				continue
+			}
+			_, masked := c.maskSensitive(*refValue, s)
+			f.Values[states[e.env].Label] = maskedValue(masked)
+		}
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// maskedValue is printed as it is, not as a JSON string
+type maskedValue string
+
+func serializeOrRaw(v any) string {
+	if m, ok := v.(maskedValue); ok {
+		return string(m)
+	}
+	s, err := serialize(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return s
+}
+
+func (c Comparer) matrixNormalizer(env int) schematicNormalizer {
+	if env == 0 {
+		return c.snL
+	}
+	return c.snR
+}
+
+// Print writes resources missing in some environments or differing between them
+func (mr MatrixResult) Print(w io.Writer) {
+	all, diff, missing := 0, 0, 0
+
+	for _, r := range mr.Resources {
+		if len(r.Environments) < len(mr.Labels) {
+			missing++
+		} else {
+			all++
+		}
+		if len(r.Fields) > 0 {
+			diff++
+		}
+		if len(r.Environments) == len(mr.Labels) && len(r.Fields) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s [%s]\n", r.Address, strings.Join(r.Environments, " "))
+		for _, f := range r.Fields {
+			fmt.Fprintf(w, "  %s\n", f.Path)
+			for _, label := range mr.Labels {
+				if v, ok := f.Values[label]; ok {
+					fmt.Fprintf(w, "    %s: %s\n", label, serializeOrRaw(v))
+				}
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "environments:              %s\n", strings.Join(mr.Labels, " "))
+	fmt.Fprintf(w, "resources in all:          %6d\n", all)
+	fmt.Fprintf(w, "resources with diff:       %6d\n", diff)
+	fmt.Fprintf(w, "resources missing in some: %6d\n", missing)
+	if len(mr.Errors) > 0 {
+		fmt.Fprintf(w, "errors:                    %6d\n", len(mr.Errors))
+	}
+}
+
+// PrintMarkdown writes a table of resources missing in some environments or differing between them
+func (mr MatrixResult) PrintMarkdown(w io.Writer) {
+	fmt.Fprintln(w, "## tfstate-diff matrix")
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "| resource | %s |\n", strings.Join(mr.Labels, " | "))
+	fmt.Fprintf(w, "|---|%s\n", strings.Repeat("---|", len(mr.Labels)))
+
+	for _, r := range mr.Resources {
+		if len(r.Environments) == len(mr.Labels) && len(r.Fields) == 0 {
+			continue
+		}
+
+		present := map[string]bool{}
+		for _, l := range r.Environments {
+			present[l] = true
+		}
+
+		cells := []string{}
+		for _, l := range mr.Labels {
+			if present[l] {
+				cells = append(cells, "✓")
+			} else {
+				cells = append(cells, "-")
+			}
+		}
+		fmt.Fprintf(w, "| %s | %s |\n", r.Address, strings.Join(cells, " | "))
+
+		for _, f := range r.Fields {
+			cells := []string{}
+			for _, l := range mr.Labels {
+				if v, ok := f.Values[l]; ok {
+					cells = append(cells, strings.ReplaceAll(codeSpan(serializeOrRaw(v)), "|", `\|`))
+				} else {
+					cells = append(cells, "")
+				}
+			}
+			fmt.Fprintf(w, "| &nbsp;&nbsp;%s | %s |\n", f.Path, strings.Join(cells, " | "))
+		}
+	}
+}
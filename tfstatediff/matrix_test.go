@@ -0,0 +1,58 @@
+package tfstatediff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareMatrix(t *testing.T) {
+	c, err := New(Config{AddressMap: []ConfigAddressMap{{Left: "aws_instance.dev_a", Right: "aws_instance.a"}}}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := func(name string, instanceType string) TfResource {
+		r := testInstance(name, instanceType)
+		r.SensitiveValues = map[string]any{"instance_type": true}
+		return r
+	}
+
+	states := []LabeledState{
+		// the first state is the left of address_map
+		{"dev", testState(testInstance("dev_a", "t3.micro"), testInstance("b", "t3.micro"), secret("s", "p1"))},
+		{"stg", testState(testInstance("a", "t3.micro"), testInstance("b", "t3.micro"), secret("s", "p1"))},
+		{"prod", testState(testInstance("a", "m5.large"), secret("s", "p2"))},
+	}
+
+	result, err := c.CompareMatrix(states)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &MatrixResult{
+		Labels: []string{"dev", "stg", "prod"},
+		Resources: []MatrixResource{
+			{
+				Address:      "aws_instance.dev_a",
+				Environments: []string{"dev", "stg", "prod"},
+				Fields:       []MatrixField{{Path: "/instance_type", Values: map[string]any{"dev": "t3.micro", "stg": "t3.micro", "prod": "m5.large"}}},
+			},
+			{Address: "aws_instance.b", Environments: []string{"dev", "stg"}, Fields: []MatrixField{}},
+			{
+				Address:      "aws_instance.s",
+				Environments: []string{"dev", "stg", "prod"},
+				// compared with the first environment
+				Fields: []MatrixField{{Path: "/instance_type", Values: map[string]any{"dev": maskedValue(sensitiveMask), "stg": maskedValue(sensitiveEqual), "prod": maskedValue(sensitiveChanged)}}},
+			},
+		},
+		Errors: []ResourceError{},
+	}
+
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	if _, err := c.CompareMatrix(states[:1]); err == nil {
+		t.Error("no error for a single state")
+	}
+}
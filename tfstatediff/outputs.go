@@ -0,0 +1,137 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wI2L/jsondiff"
+)
+
+type TfOutput struct {
+	Sensitive bool `json:"sensitive"`
+	Value     any  `json:"value"`
+	Type      any  `json:"type,omitempty"`
+}
+
+// compareOutputs compares root module outputs by name. Values of outputs sensitive on either side are masked unless showSensitive.
+func (c Comparer) compareOutputs(l map[string]TfOutput, r map[string]TfOutput) ([]ResourceDiff, error) {
+	vsL := map[string]any{}
+	for name, o := range l {
+		vsL[name] = o.Value
+	}
+	vsR := map[string]any{}
+	for name, o := range r {
+		vsR[name] = o.Value
+	}
+
+	// references are normalized as in resources
+	vsL = c.inL.normalize(vsL)
+	vsR = c.inR.normalize(vsR)
+
+	names := []string{}
+	for name := range l {
+		names = append(names, name)
+	}
+	for name := range r {
+		if _, ok := l[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []ResourceDiff{}
+
+	fmt.Fprintln(c.wDetail, "compare outputs")
+	for _, name := range names {
+		oL, okL := l[name]
+		oR, okR := r[name]
+		sensitive := (oL.Sensitive || oR.Sensitive) && !c.showSensitive
+
+		mask := func(v any) (string, error) {
+			if sensitive {
+				return sensitiveChanged, nil
+			}
+			return serialize(v)
+		}
+
+		switch {
+		case !okR:
+			old, err := mask(vsL[name])
+			if err != nil {
+				return nil, err
+			}
+			f := FieldDiff{Path: "", OldValue: old, NewValue: "null"}
+			if sensitive {
+				f.oldHash = c.sensitiveHash(serializeOrRaw(vsL[name]))
+			}
+			fmt.Fprintf(c.wDetail, "  - output.%s : %s\n", name, old)
+			diffs = append(diffs, ResourceDiff{Name: name, Change: ChangeRemoved, Fields: []FieldDiff{f}})
+		case !okL:
+			new, err := mask(vsR[name])
+			if err != nil {
+				return nil, err
+			}
+			f := FieldDiff{Path: "", OldValue: "null", NewValue: new}
+			if sensitive {
+				f.newHash = c.sensitiveHash(serializeOrRaw(vsR[name]))
+			}
+			fmt.Fprintf(c.wDetail, "  + output.%s : %s\n", name, new)
+			diffs = append(diffs, ResourceDiff{Name: name, Change: ChangeAdded, Fields: []FieldDiff{f}})
+		default:
+			od, err := c.compareOutput(name, vsL[name], vsR[name], sensitive)
+			if err != nil {
+				return nil, err
+			}
+			if len(od.Fields) > 0 {
+				diffs = append(diffs, *od)
+			}
+		}
+	}
+	fmt.Fprintln(c.wDetail, "")
+
+	return diffs, nil
+}
+
+func (c Comparer) compareOutput(name string, l any, r any, sensitive bool) (*ResourceDiff, error) {
+	address := "output." + name
+	od := ResourceDiff{Name: name, Change: ChangeChanged}
+
+	patch, err := jsondiff.CompareOpts(l, r, jsondiff.Equivalent())
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range patch {
+		path := patch[k].Path.String()
+		if rule := c.ignoredBy(address, "", path, patch[k].OldValue, patch[k].Value); rule != "" {
+			if sensitive {
+				old, new := c.maskSensitive(serializeOrRaw(patch[k].OldValue), serializeOrRaw(patch[k].Value))
+				c.record(address, path, old, new, rule)
+			} else {
+				c.suppress(address, path, patch[k].OldValue, patch[k].Value, rule)
+			}
+			continue
+		}
+
+		if sensitive {
+			// neither values nor paths are revealed
+			f := c.sensitiveField("", serializeOrRaw(l), serializeOrRaw(r))
+			fmt.Fprintf(c.wDetail, "  %s : %s -> %s\n", address, f.OldValue, f.NewValue)
+			od.Fields = []FieldDiff{f}
+			break
+		}
+
+		old, err := serialize(patch[k].OldValue)
+		if err != nil {
+			return nil, err
+		}
+		new, err := serialize(patch[k].Value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(c.wDetail, "  %s%s : %s -> %s\n", address, path, old, new)
+		od.Fields = append(od.Fields, FieldDiff{Path: path, OldValue: old, NewValue: new})
+	}
+
+	return &od, nil
+}
@@ -0,0 +1,81 @@
+package tfstatediff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareOutputs(t *testing.T) {
+	values := func(env string, outputs map[string]TfOutput) TfValues {
+		r := testInstance("a", "t3.micro")
+		r.Values["arn"] = "arn:aws:ec2:instance/i-" + env
+		return TfValues{Outputs: outputs, RootModule: TfModule{Resources: []TfResource{r}}}
+	}
+
+	l := values("stg", map[string]TfOutput{
+		"same":             {Value: "a"},
+		"changed":          {Value: map[string]any{"a": 1.0, "b": "x"}},
+		"removed":          {Value: "a"},
+		"reference":        {Value: "arn:aws:ec2:instance/i-stg"},
+		"secret_changed":   {Sensitive: true, Value: "p1"},
+		"secret_equal":     {Sensitive: true, Value: "p1"},
+		"secret_removed":   {Sensitive: true, Value: "p1"},
+		"secret_one_sided": {Value: "p1"},
+	})
+	r := values("prod", map[string]TfOutput{
+		"same":             {Value: "a"},
+		"changed":          {Value: map[string]any{"a": 2.0, "b": "x"}},
+		"added":            {Value: []any{"a"}},
+		"reference":        {Value: "arn:aws:ec2:instance/i-prod"},
+		"secret_changed":   {Sensitive: true, Value: "p2"},
+		"secret_equal":     {Sensitive: true, Value: "p1"},
+		"secret_one_sided": {Sensitive: true, Value: "p2"},
+	})
+
+	c, err := New(Config{}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.compareValues(l, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, od := range d.OutputDiffs {
+		for _, f := range od.Fields {
+			got = append(got, strings.Join([]string{od.Name, od.Change, f.Path, f.OldValue.(string), f.NewValue.(string)}, " "))
+		}
+	}
+	want := []string{
+		`added added  null ["a"]`,
+		`changed changed /a 1 2`,
+		`removed removed  "a" null`,
+		`secret_changed changed  (sensitive: changed) (sensitive: changed)`,
+		// sensitive on either side
+		`secret_one_sided changed  (sensitive: changed) (sensitive: changed)`,
+		`secret_removed removed  (sensitive: changed) null`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	c.SetShowSensitive(true)
+	d, err = c.compareValues(l, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, od := range d.OutputDiffs {
+		if od.Name == "secret_changed" {
+			found = true
+			if od.Fields[0].OldValue != `"p1"` || od.Fields[0].NewValue != `"p2"` {
+				t.Errorf("with -show-sensitive: %+v", od.Fields)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("with -show-sensitive: %+v", d.OutputDiffs)
+	}
+}
@@ -0,0 +1,316 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+type policyStatement struct {
+	// to match statements between left and right
+	key string
+	// Sid, or the index in the policy
+	name  string
+	value map[string]any
+}
+
+// elements of these keys are sets of strings, which may contain wildcards
+var policyListKeys = []string{"Action", "NotAction", "Resource", "NotResource"}
+
+// parsePolicyStatements returns normalized statements, or false if doc is not an IAM policy
+func (n schematicNormalizer) parsePolicyStatements(doc map[string]any) ([]policyStatement, bool, error) {
+	var raw []any
+	switch st := doc["Statement"].(type) {
+	case nil:
+		if len(doc) > 0 {
+			return nil, false, nil
+		}
+	case map[string]any:
+		raw = []any{st}
+	case []any:
+		raw = st
+	default:
+		return nil, false, nil
+	}
+
+	statements := []policyStatement{}
+	merged := map[string]int{}
+
+	for i := range raw {
+		m, ok := raw[i].(map[string]any)
+		if !ok {
+			return nil, false, nil
+		}
+		v := normalizeStatement(m)
+
+		sid, _ := v["Sid"].(string)
+		if sid != "" {
+			statements = append(statements, policyStatement{key: "Sid:" + sid, name: sid, value: v})
+			continue
+		}
+
+		key, err := n.statementKey(v)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// statements split only by actions are merged
+		if _, ok := v["NotAction"]; !ok {
+			if j, ok := merged[key]; ok {
+				statements[j].value["Action"] = mergePolicyList(statements[j].value["Action"], v["Action"])
+				continue
+			}
+			merged[key] = len(statements)
+		}
+		statements = append(statements, policyStatement{key: key, name: fmt.Sprintf("#%d", i), value: v})
+	}
+
+	return statements, true, nil
+}
+
+// statementKey identifies a statement without Sid by its effect, principals, resources and conditions
+func (n schematicNormalizer) statementKey(v map[string]any) (string, error) {
+	k := map[string]any{}
+	for _, name := range []string{"Effect", "Principal", "NotPrincipal", "Resource", "NotResource", "Condition"} {
+		if val, ok := v[name]; ok {
+			k[name] = val
+		}
+	}
+	if _, ok := v["NotAction"]; ok {
+		k["NotAction"] = v["NotAction"]
+	}
+
+	// values differing only by ignore_diff should be matched
+	return n.serializeForSort(k)
+}
+
+func normalizeStatement(st map[string]any) map[string]any {
+	v := map[string]any{}
+
+	for k, val := range st {
+		v[k] = val
+	}
+
+	for _, k := range policyListKeys {
+		if val, ok := v[k]; ok {
+			v[k] = mergePolicyList(nil, val)
+		}
+	}
+
+	for _, k := range []string{"Principal", "NotPrincipal"} {
+		if p, ok := v[k].(map[string]any); ok {
+			np := map[string]any{}
+			for pk, pv := range p {
+				np[pk] = mergePolicyList(nil, pv)
+			}
+			v[k] = np
+		}
+	}
+
+	if c, ok := v["Condition"].(map[string]any); ok {
+		nc := map[string]any{}
+		for op, kv := range c {
+			if m, ok := kv.(map[string]any); ok {
+				nm := map[string]any{}
+				for ck, cv := range m {
+					nm[ck] = mergePolicyList(nil, cv)
+				}
+				nc[op] = nm
+			} else {
+				nc[op] = kv
+			}
+		}
+		v["Condition"] = nc
+	}
+
+	return v
+}
+
+// mergePolicyList returns the sorted union of a string or a list of strings,
+// omitting elements covered by wildcards of other elements
+func mergePolicyList(a any, b any) any {
+	set := map[string]bool{}
+	for _, v := range []any{a, b} {
+		switch vs := v.(type) {
+		case nil:
+		case string:
+			set[vs] = true
+		case []any:
+			for i := range vs {
+				s, ok := vs[i].(string)
+				if !ok {
+					return appendPolicyList(a, b)
+				}
+				set[s] = true
+			}
+		default:
+			return appendPolicyList(a, b)
+		}
+	}
+
+	patterns := map[string]*regexp.Regexp{}
+	for s := range set {
+		if strings.ContainsAny(s, "*?") {
+			patterns[s] = wildcardPattern(s)
+		}
+	}
+
+	list := []string{}
+outer:
+	for s := range set {
+		for src, p := range patterns {
+			// s is covered by a broader pattern
+			if src != s && p.MatchString(s) && !(patterns[s] != nil && patterns[s].MatchString(src)) {
+				continue outer
+			}
+		}
+		list = append(list, s)
+	}
+	sort.Strings(list)
+
+	result := make([]any, len(list))
+	for i := range list {
+		result[i] = list[i]
+	}
+	return result
+}
+
+// appendPolicyList concatenates values which are not strings or lists of strings, keeping them as they are
+func appendPolicyList(a any, b any) any {
+	if a == nil {
+		return b
+	}
+
+	list := []any{}
+	for _, v := range []any{a, b} {
+		switch vs := v.(type) {
+		case nil:
+		case []any:
+			list = append(list, vs...)
+		default:
+			list = append(list, vs)
+		}
+	}
+	return list
+}
+
+// wildcardPattern converts an IAM wildcard (* and ?) into a regexp
+func wildcardPattern(s string) *regexp.Regexp {
+	p := regexp.QuoteMeta(s)
+	p = strings.ReplaceAll(p, `\*`, ".*")
+	p = strings.ReplaceAll(p, `\?`, ".")
+	return regexp.MustCompile("^" + p + "$")
+}
+
+// comparePolicyStatements matches statements by Sid (or effect, principals and resources)
+// and reports added, removed and changed statements
+func (c Comparer) comparePolicyStatements(address string, path string, l []policyStatement, r []policyStatement) ([]ResourceDiff, error) {
+	diffs := []ResourceDiff{}
+
+	indexR := map[string][]int{}
+	for j := range r {
+		indexR[r[j].key] = append(indexR[r[j].key], j)
+	}
+	foundR := map[int]bool{}
+
+	for i := range l {
+		js := indexR[l[i].key]
+		if len(js) == 0 {
+			old, err := serialize(l[i].value)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(c.wDetail, "    - Statement %s : %s\n", l[i].name, old)
+			diffs = append(diffs, ResourceDiff{
+				Name:   "Statement " + l[i].name,
+				Change: ChangeRemoved,
+				Fields: []FieldDiff{{Path: "", OldValue: old, NewValue: "null"}},
+			})
+			continue
+		}
+		j := js[0]
+		indexR[l[i].key] = js[1:]
+		foundR[j] = true
+
+		sd := ResourceDiff{Name: "Statement " + l[i].name, Change: ChangeChanged}
+		basePath := fmt.Sprintf("%s/Statement/%s", path, l[i].name)
+
+		keys := map[string]bool{}
+		for k := range l[i].value {
+			keys[k] = true
+		}
+		for k := range r[j].value {
+			keys[k] = true
+		}
+		sorted := []string{}
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			vl, vr := l[i].value[k], r[j].value[k]
+			if reflect.DeepEqual(vl, vr) {
+				continue
+			}
+			p := "/" + k
+			if rule := c.ignoredBy(address, basePath, p, vl, vr); rule != "" {
+				c.suppress(address, basePath+p, vl, vr, rule)
+				continue
+			}
+			old, err := serialize(vl)
+			if err != nil {
+				return nil, err
+			}
+			new, err := serialize(vr)
+			if err != nil {
+				return nil, err
+			}
+			sd.Fields = append(sd.Fields, FieldDiff{Path: p, OldValue: old, NewValue: new})
+		}
+
+		if len(sd.Fields) > 0 {
+			fmt.Fprintf(c.wDetail, "    ~ Statement %s\n", l[i].name)
+			for _, f := range sd.Fields {
+				fmt.Fprintf(c.wDetail, "      %s : %s -> %s\n", f.Path, f.OldValue, f.NewValue)
+			}
+			diffs = append(diffs, sd)
+		}
+	}
+
+	for j := range r {
+		if foundR[j] {
+			continue
+		}
+		new, err := serialize(r[j].value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(c.wDetail, "    + Statement %s : %s\n", r[j].name, new)
+		diffs = append(diffs, ResourceDiff{
+			Name:   "Statement " + r[j].name,
+			Change: ChangeAdded,
+			Fields: []FieldDiff{{Path: "", OldValue: "null", NewValue: new}},
+		})
+	}
+
+	return diffs, nil
+}
+
+func withoutStatement(doc map[string]any) map[string]any {
+	m := map[string]any{}
+	for k, v := range doc {
+		if k != "Statement" {
+			m[k] = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,222 @@
+package tfstatediff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergePolicyList(t *testing.T) {
+	tests := []struct {
+		name string
+		a    any
+		b    any
+		want any
+	}{
+		{"string", nil, "s3:GetObject", []any{"s3:GetObject"}},
+		{"string and list", "s3:PutObject", []any{"s3:GetObject"}, []any{"s3:GetObject", "s3:PutObject"}},
+		{"duplicates", []any{"s3:GetObject"}, []any{"s3:GetObject"}, []any{"s3:GetObject"}},
+		{"covered by wildcard", []any{"s3:*"}, []any{"s3:GetObject", "ec2:DescribeInstances"}, []any{"ec2:DescribeInstances", "s3:*"}},
+		{"narrower wildcard", []any{"s3:Get*"}, []any{"s3:*"}, []any{"s3:*"}},
+		{"question mark", []any{"s3:GetObjec?"}, []any{"s3:GetObject"}, []any{"s3:GetObjec?"}},
+		{"same wildcards", []any{"s3:*"}, "s3:*", []any{"s3:*"}},
+		{"not strings alone", nil, []any{1.0, "a"}, []any{1.0, "a"}},
+		{"not strings in b", []any{"s3:GetObject"}, []any{map[string]any{"x": "y"}}, []any{"s3:GetObject", map[string]any{"x": "y"}}},
+		{"not strings in a", []any{1.0}, "s3:GetObject", []any{1.0, "s3:GetObject"}},
+		{"number", "s3:GetObject", 1.0, []any{"s3:GetObject", 1.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePolicyList(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePolicyList(%#v, %#v) = %#v, want %#v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func parsePolicy(t *testing.T, n schematicNormalizer, doc string) []policyStatement {
+	t.Helper()
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(doc), &data); err != nil {
+		t.Fatal(err)
+	}
+	sts, ok, err := n.parsePolicyStatements(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("not a policy: %s", doc)
+	}
+	return sts
+}
+
+func TestParsePolicyStatements(t *testing.T) {
+	n := newSchematicNormalizer(nil, TfProvidersSchema{})
+
+	tests := []struct {
+		name string
+		doc  string
+		// names and actions of statements
+		want map[string]any
+	}{
+		{
+			name: "sid",
+			doc:  `{"Statement":[{"Sid":"Read","Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`,
+			want: map[string]any{"Read": []any{"s3:GetObject"}},
+		},
+		{
+			name: "single statement object",
+			doc:  `{"Statement":{"Effect":"Allow","Action":["s3:GetObject"],"Resource":"*"}}`,
+			want: map[string]any{"#0": []any{"s3:GetObject"}},
+		},
+		{
+			name: "split by actions",
+			doc: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"},
+				{"Effect":"Allow","Action":["s3:PutObject","s3:GetObject"],"Resource":["*"]}
+			]}`,
+			want: map[string]any{"#0": []any{"s3:GetObject", "s3:PutObject"}},
+		},
+		{
+			name: "split by actions covered by wildcard",
+			doc: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"},
+				{"Effect":"Allow","Action":"s3:*","Resource":"*"}
+			]}`,
+			want: map[string]any{"#0": []any{"s3:*"}},
+		},
+		{
+			name: "different resources",
+			doc: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::a/*"},
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::b/*"}
+			]}`,
+			want: map[string]any{"#0": []any{"s3:GetObject"}, "#1": []any{"s3:GetObject"}},
+		},
+		{
+			name: "sid not merged",
+			doc: `{"Statement":[
+				{"Sid":"A","Effect":"Allow","Action":"s3:GetObject","Resource":"*"},
+				{"Sid":"B","Effect":"Allow","Action":"s3:PutObject","Resource":"*"}
+			]}`,
+			want: map[string]any{"A": []any{"s3:GetObject"}, "B": []any{"s3:PutObject"}},
+		},
+		{
+			name: "NotAction not merged",
+			doc: `{"Statement":[
+				{"Effect":"Deny","NotAction":"s3:GetObject","Resource":"*"},
+				{"Effect":"Deny","NotAction":"s3:PutObject","Resource":"*"}
+			]}`,
+			want: map[string]any{"#0": nil, "#1": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]any{}
+			for _, st := range parsePolicy(t, n, tt.doc) {
+				got[st.name] = st.value["Action"]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicyStatementsNotPolicy(t *testing.T) {
+	n := newSchematicNormalizer(nil, TfProvidersSchema{})
+
+	for _, doc := range []map[string]any{
+		{"Version": "2012-10-17", "foo": "bar"},
+		{"Statement": "x"},
+		{"Statement": []any{"x"}},
+	} {
+		if _, ok, err := n.parsePolicyStatements(doc); ok || err != nil {
+			t.Errorf("%#v: ok = %v, err = %v", doc, ok, err)
+		}
+	}
+}
+
+func TestComparePolicyStatements(t *testing.T) {
+	c, err := New(Config{}, TfProvidersSchema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		l    string
+		r    string
+		// change by statement name
+		want map[string]string
+	}{
+		{
+			name: "string equals list",
+			l:    `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Principal":{"AWS":"arn:aws:iam::1:root"},"Resource":"*"}]}`,
+			r:    `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject"],"Principal":{"AWS":["arn:aws:iam::1:root"]},"Resource":["*"]}]}`,
+			want: map[string]string{},
+		},
+		{
+			name: "matched by sid",
+			l:    `{"Statement":[{"Sid":"Read","Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::a/*"}]}`,
+			r:    `{"Statement":[{"Sid":"Read","Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::b/*"}]}`,
+			want: map[string]string{"Statement Read": ChangeChanged},
+		},
+		{
+			name: "matched by key",
+			l:    `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"}]}`,
+			r:    `{"Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:PutObject"],"Resource":"*"}]}`,
+			want: map[string]string{"Statement #0": ChangeChanged},
+		},
+		{
+			name: "different keys",
+			l:    `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::a/*"}]}`,
+			r:    `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::b/*"}]}`,
+			want: map[string]string{"Statement #0": ChangeRemoved + "," + ChangeAdded},
+		},
+		{
+			name: "split and merged",
+			l: `{"Statement":[
+				{"Effect":"Allow","Action":"s3:GetObject","Resource":"*"},
+				{"Effect":"Allow","Action":"s3:PutObject","Resource":"*"}
+			]}`,
+			r:    `{"Statement":[{"Effect":"Allow","Action":["s3:PutObject","s3:GetObject"],"Resource":"*"}]}`,
+			want: map[string]string{},
+		},
+		{
+			name: "order",
+			l: `{"Statement":[
+				{"Sid":"A","Effect":"Allow","Action":"s3:GetObject","Resource":"*"},
+				{"Sid":"B","Effect":"Deny","Action":"s3:DeleteObject","Resource":"*"}
+			]}`,
+			r: `{"Statement":[
+				{"Sid":"B","Effect":"Deny","Action":"s3:DeleteObject","Resource":"*"},
+				{"Sid":"A","Effect":"Allow","Action":"s3:GetObject","Resource":"*"}
+			]}`,
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs, err := c.comparePolicyStatements("aws_iam_policy.p", "/policy", parsePolicy(t, c.snL, tt.l), parsePolicy(t, c.snR, tt.r))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]string{}
+			for _, d := range diffs {
+				if got[d.Name] != "" {
+					got[d.Name] += ","
+				}
+				got[d.Name] += d.Change
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
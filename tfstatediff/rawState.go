@@ -0,0 +1,157 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// raw state file (terraform.tfstate) of format version 4
+
+type tfRawState struct {
+	Version          int                 `json:"version"`
+	TerraformVersion string              `json:"terraform_version"`
+	Outputs          map[string]TfOutput `json:"outputs"`
+	Resources        []tfRawResource     `json:"resources"`
+}
+
+type tfRawResource struct {
+	Module    string          `json:"module,omitempty"`
+	Mode      string          `json:"mode"`
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Provider  string          `json:"provider"`
+	Instances []tfRawInstance `json:"instances"`
+}
+
+type tfRawInstance struct {
+	IndexKey            any               `json:"index_key,omitempty"` // number or string
+	Attributes          map[string]any    `json:"attributes"`
+	SensitiveAttributes [][]tfRawPathStep `json:"sensitive_attributes,omitempty"`
+}
+
+// e.g. {"type": "get_attr", "value": "password"} or {"type": "index", "value": {"value": 0, "type": "number"}}
+type tfRawPathStep struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// e.g. module.a.provider["registry.terraform.io/hashicorp/aws"].alias
+var providerConfigPattern = regexp.MustCompile(`provider\["([^"]+)"\]`)
+
+func (s tfRawState) toState() (*TfState, error) {
+	if s.Version != 4 {
+		return nil, fmt.Errorf("unsupported state version: %d", s.Version)
+	}
+
+	resources := []TfResource{}
+
+	for _, r := range s.Resources {
+		m := providerConfigPattern.FindStringSubmatch(r.Provider)
+		if m == nil {
+			return nil, fmt.Errorf("invalid provider of %s.%s: %s", r.Type, r.Name, r.Provider)
+		}
+
+		base := fmt.Sprintf("%s.%s", r.Type, r.Name)
+		if r.Mode == "data" {
+			base = "data." + base
+		}
+		if r.Module != "" {
+			base = r.Module + "." + base
+		}
+
+		for _, i := range r.Instances {
+			address := base
+			switch k := i.IndexKey.(type) {
+			case nil:
+			case float64:
+				address += fmt.Sprintf("[%d]", int(k))
+			case string:
+				address += fmt.Sprintf("[%q]", k)
+			default:
+				return nil, fmt.Errorf("invalid index_key of %s: %#v", base, k)
+			}
+
+			sv, err := sensitiveValues(i.SensitiveAttributes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sensitive_attributes of %s: %w", address, err)
+			}
+
+			resources = append(resources, TfResource{
+				Address:         address,
+				Mode:            r.Mode,
+				Type:            r.Type,
+				Name:            r.Name,
+				ProviderName:    m[1],
+				Values:          i.Attributes,
+				SensitiveValues: sv,
+			})
+		}
+	}
+
+	return &TfState{
+		TerraformVersion: s.TerraformVersion,
+		Values:           &TfValues{Outputs: s.Outputs, RootModule: TfModule{Resources: resources}},
+	}, nil
+}
+
+// sensitiveValues converts sensitive_attributes to the form of sensitive_values, with map keys as they are.
+// Lists and sets are sensitive as a whole if any element is, since normalization may reorder them.
+func sensitiveValues(paths [][]tfRawPathStep) (map[string]any, error) {
+	sv := map[string]any{}
+
+	for _, path := range paths {
+		node := sv
+		for k, step := range path {
+			var key string
+			switch step.Type {
+			case "get_attr":
+				s, ok := step.Value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%#v", step.Value)
+				}
+				key = s
+			case "index":
+				index, ok := step.Value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%#v", step.Value)
+				}
+				switch v := index["value"].(type) {
+				case float64:
+					key = fmt.Sprintf("%d", int(v))
+				case string:
+					key = v
+				default:
+					return nil, fmt.Errorf("%#v", step.Value)
+				}
+			default:
+				return nil, fmt.Errorf("unknown step type: %s", step.Type)
+			}
+
+			if k == len(path)-1 || node[key] == true || isListIndex(path[k+1]) {
+				// the whole value, or the list or set holding the sensitive element, is sensitive
+				node[key] = true
+				break
+			}
+			child, ok := node[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[key] = child
+			}
+			node = child
+		}
+	}
+
+	return sv, nil
+}
+
+func isListIndex(step tfRawPathStep) bool {
+	if step.Type != "index" {
+		return false
+	}
+	index, ok := step.Value.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, ok = index["value"].(float64)
+	return ok
+}
@@ -0,0 +1,92 @@
+package tfstatediff
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRawStateToState(t *testing.T) {
+	raw := `{
+		"version": 4,
+		"terraform_version": "1.5.0",
+		"resources": [
+			{
+				"mode": "managed", "type": "aws_instance", "name": "a",
+				"provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+				"instances": [
+					{"index_key": 0, "attributes": {"id": "i-0"}},
+					{"index_key": 1, "attributes": {"id": "i-1"}}
+				]
+			},
+			{
+				"module": "module.x", "mode": "data", "type": "aws_ami", "name": "b",
+				"provider": "module.x.provider[\"registry.terraform.io/hashicorp/aws\"].alias",
+				"instances": [{"index_key": "k", "attributes": {"id": "ami-1"}}]
+			},
+			{
+				"mode": "managed", "type": "aws_db_instance", "name": "c",
+				"provider": "provider[\"registry.terraform.io/hashicorp/aws\"]",
+				"instances": [{
+					"attributes": {"id": "db-1"},
+					"sensitive_attributes": [
+						[{"type": "get_attr", "value": "password"}],
+						[{"type": "get_attr", "value": "tags"}, {"type": "index", "value": {"value": "secret", "type": "string"}}],
+						[{"type": "get_attr", "value": "users"}, {"type": "index", "value": {"value": 1, "type": "number"}}, {"type": "get_attr", "value": "password"}]
+					]
+				}]
+			}
+		]
+	}`
+
+	var s tfRawState
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	state, err := s.toState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []TfResource{
+		{Address: "aws_instance.a[0]", Mode: "managed", Type: "aws_instance", Name: "a", ProviderName: "registry.terraform.io/hashicorp/aws", Values: map[string]any{"id": "i-0"}, SensitiveValues: map[string]any{}},
+		{Address: "aws_instance.a[1]", Mode: "managed", Type: "aws_instance", Name: "a", ProviderName: "registry.terraform.io/hashicorp/aws", Values: map[string]any{"id": "i-1"}, SensitiveValues: map[string]any{}},
+		{Address: `module.x.data.aws_ami.b["k"]`, Mode: "data", Type: "aws_ami", Name: "b", ProviderName: "registry.terraform.io/hashicorp/aws", Values: map[string]any{"id": "ami-1"}, SensitiveValues: map[string]any{}},
+		{
+			Address: "aws_db_instance.c", Mode: "managed", Type: "aws_db_instance", Name: "c", ProviderName: "registry.terraform.io/hashicorp/aws", Values: map[string]any{"id": "db-1"},
+			// lists with a sensitive element are sensitive as a whole
+			SensitiveValues: map[string]any{"password": true, "tags": map[string]any{"secret": true}, "users": true},
+		},
+	}
+
+	if state.TerraformVersion != "1.5.0" {
+		t.Errorf("terraform_version: %s", state.TerraformVersion)
+	}
+	if got := state.Values.RootModule.resources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestRawStateToStateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"version", `{"version": 3}`},
+		{"provider", `{"version": 4, "resources": [{"mode": "managed", "type": "t", "name": "a", "provider": "aws", "instances": []}]}`},
+		{"index_key", `{"version": 4, "resources": [{"mode": "managed", "type": "t", "name": "a", "provider": "provider[\"p\"]", "instances": [{"index_key": true}]}]}`},
+		{"sensitive_attributes", `{"version": 4, "resources": [{"mode": "managed", "type": "t", "name": "a", "provider": "provider[\"p\"]", "instances": [{"sensitive_attributes": [[{"type": "unknown", "value": "x"}]]}]}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s tfRawState
+			if err := json.Unmarshal([]byte(tt.raw), &s); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.toState(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package tfstatediff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type schematicNormalizer struct {
+	ps  TfProvidersSchema
+	rrs []ignoreDiffRule
+}
+
+func newSchematicNormalizer(rrs []ignoreDiffRule, ps TfProvidersSchema) schematicNormalizer {
+	return schematicNormalizer{
+		ps,
+		rrs,
+	}
+}
+
+func (n schematicNormalizer) normalize(r TfResource) (TfResource, error) {
+	s, err := n.findSchema(r)
+	if err != nil {
+		return r, err
+	}
+
+	vs, err := transformMap(r.Values, "", func(path string, value any) (any, error) {
+		if value == nil {
+			return nil, nil
+		}
+
+		set, err := isSet(s, path)
+		if err != nil {
+			return nil, err
+		}
+		if set {
+			if vals, ok := value.([]any); ok {
+				return n.sort(vals)
+			}
+			return nil, fmt.Errorf("invalid value with set type: %s", path)
+		}
+
+		if strings.HasSuffix(path, "/policy") || strings.HasSuffix(path, "/inline_policy") || strings.HasSuffix(path, "/assume_role_policy") {
+			if val, ok := value.(string); ok {
+				return n.normalizePolicy(val)
+			}
+			return nil, fmt.Errorf("invalid type of policy value: %s", path)
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		return r, err
+	}
+
+	return TfResource{
+		Address:         r.Address,
+		Mode:            r.Mode,
+		Type:            r.Type,
+		Name:            r.Name,
+		ProviderName:    r.ProviderName,
+		Values:          vs,
+		SensitiveValues: r.SensitiveValues,
+	}, nil
+}
+
+func (n schematicNormalizer) normalizePolicy(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	var data any
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return "", err
+	}
+
+	result, err := jsonTransform(data, "", func(path string, val any) (any, error) {
+		if a, ok := val.([]any); ok {
+			return n.sort(a)
+		}
+		return val, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	return string(s), nil
+}
+
+type sortable struct {
+	value      any
+	serialized string
+}
+
+func (n schematicNormalizer) sort(vs []any) ([]any, error) {
+	svs := make([]sortable, len(vs))
+
+	for i := range vs {
+		serialized, err := n.serializeForSort(vs[i])
+		if err != nil {
+			return nil, err
+		}
+		svs[i] = sortable{
+			value:      vs[i],
+			serialized: serialized,
+		}
+	}
+
+	sort.Slice(svs, func(i int, j int) bool {
+		return strings.Compare(svs[i].serialized, svs[j].serialized) < 0
+	})
+
+	result := make([]any, len(vs))
+
+	for i := range svs {
+		result[i] = svs[i].value
+	}
+
+	return result, nil
+}
+
+func (n schematicNormalizer) serializeForSort(v any) (string, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	s := string(bytes)
+
+	for i := range n.rrs {
+		s = n.rrs[i].rewrite(s)
+	}
+
+	return s, nil
+}
+
+type jsonTransformer func(path string, value any) (any, error)
+
+func jsonTransform(data any, path string, t jsonTransformer) (any, error) {
+	if m, ok := data.(map[string]any); ok {
+		return transformMap(m, path, t)
+	} else if a, ok := data.([]any); ok {
+		return transformArray(a, path, t)
+	}
+
+	return t(path, data)
+}
+
+func transformMap(data map[string]any, path string, t jsonTransformer) (map[string]any, error) {
+	result := map[string]any{}
+
+	for k, v := range data {
+		tv, err := jsonTransform(v, fmt.Sprintf("%s/%s", path, k), t)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = tv
+	}
+
+	return result, nil
+}
+
+func transformArray(data []any, path string, t jsonTransformer) ([]any, error) {
+	result := make([]any, len(data))
+
+	for k, v := range data {
+		tv, err := jsonTransform(v, fmt.Sprintf("%s/%d", path, k), t)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = tv
+	}
+
+	tr, err := t(path, result)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := tr.([]any)
+	if !ok {
+		return nil, fmt.Errorf("array transformed into non-array: %s", path)
+	}
+	return a, nil
+}
+
+func isSet(s TfSchema, path string) (bool, error) {
+	i := strings.Index(path[1:], "/")
+
+	var p string
+	if i < 0 {
+		p = path[1:]
+	} else {
+		p = path[1 : 1+i]
+	}
+
+	if a, ok := s.Block.Attributes[p]; ok {
+		if ts, ok := a.Type.([]any); ok {
+			for j := range ts {
+				if ts[j] == "set" {
+					// Values in an attribute has no schema
+					return i < 0, nil
+				}
+			}
+			return false, nil
+		}
+		return a.Type == "set", nil
+	}
+
+	if a, ok := s.Block.BlockTypes[p]; ok {
+		if i < 0 {
+			return a.NestingMode == "set", nil
+		}
+
+		j := strings.Index(path[1+i+1:], "/")
+		if j >= 0 {
+			i += 1 + j
+		}
+		return isSet(a, path[1+i:])
+	}
+
+	return false, fmt.Errorf("schema attribute not found: %s", path)
+}
+
+func (n schematicNormalizer) findSchema(r TfResource) (TfSchema, error) {
+	ps := n.ps.ProviderSchema[r.ProviderName]
+	if r.Mode == "data" {
+		if s, ok := ps.DataSourceSchemas[r.Type]; ok {
+			return s, nil
+		}
+		return TfSchema{}, fmt.Errorf("schema not found: %s (%s)", r.Type, r.ProviderName)
+	}
+	if s, ok := ps.ResourceSchemas[r.Type]; ok {
+		return s, nil
+	}
+	return TfSchema{}, fmt.Errorf("schema not found: %s (%s)", r.Type, r.ProviderName)
+}
@@ -0,0 +1,107 @@
+package tfstatediff
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	sensitiveMask    = "(sensitive)"
+	sensitiveChanged = "(sensitive: changed)"
+	sensitiveEqual   = "(sensitive: equal)"
+)
+
+// SetShowSensitive makes the comparison print sensitive values as they are
+func (c *Comparer) SetShowSensitive(show bool) {
+	c.showSensitive = show
+}
+
+// isSensitive tells whether the path is sensitive in either side of the resource, or contains sensitive values
+func (c Comparer) isSensitive(address string, path string) bool {
+	if c.showSensitive {
+		return false
+	}
+	for _, sv := range c.sensitive[address] {
+		if isSensitivePath(sv, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitivePath walks sensitive_values, which has true at sensitive values, along the JSON pointer.
+// Lists and sets are sensitive as a whole if any element is, since the path is taken after normalization may reorder them.
+func isSensitivePath(sv any, path string) bool {
+	node := sv
+	tokens := []string{}
+	if path != "" {
+		tokens = strings.Split(path[1:], "/")
+	}
+
+	for _, t := range tokens {
+		if b, ok := node.(bool); ok {
+			return b
+		}
+		// unescape JSON pointer
+		t = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[t]
+		case []any:
+			return containsSensitive(n)
+		default:
+			return false
+		}
+	}
+
+	return containsSensitive(node)
+}
+
+func containsSensitive(node any) bool {
+	switch n := node.(type) {
+	case bool:
+		return n
+	case map[string]any:
+		for _, v := range n {
+			if containsSensitive(v) {
+				return true
+			}
+		}
+	case []any:
+		for _, v := range n {
+			if containsSensitive(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sensitiveHash identifies a serialized sensitive value without revealing it.
+// The salt is random for each Comparer, so the hashes are never comparable outside of it.
+func (c Comparer) sensitiveHash(s string) string {
+	h := hmac.New(sha256.New, c.salt)
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// maskSensitive replaces serialized values by whether they are equal, compared by hashes
+func (c Comparer) maskSensitive(old string, new string) (string, string) {
+	if c.sensitiveHash(old) == c.sensitiveHash(new) {
+		return sensitiveEqual, sensitiveEqual
+	}
+	return sensitiveChanged, sensitiveChanged
+}
+
+// sensitiveField is a masked FieldDiff keeping the hashes, so that three-way comparisons tell changes apart
+func (c Comparer) sensitiveField(path string, old string, new string) FieldDiff {
+	f := FieldDiff{Path: path, oldHash: c.sensitiveHash(old), newHash: c.sensitiveHash(new)}
+	f.OldValue, f.NewValue = c.maskSensitive(old, new)
+	return f
+}
+
+func (f FieldDiff) masked() bool {
+	return f.oldHash != "" || f.newHash != ""
+}
@@ -0,0 +1,125 @@
+package tfstatediff
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func sensitiveInstance(name string, instanceType string) TfResource {
+	r := testInstance(name, instanceType)
+	r.SensitiveValues = map[string]any{"instance_type": true}
+	return r
+}
+
+func TestBaselineSensitive(t *testing.T) {
+	p1 := testState(sensitiveInstance("a", "p1"))
+	p2 := testState(sensitiveInstance("a", "p2"))
+	p3 := testState(sensitiveInstance("a", "p3"))
+
+	baseline := func(showSensitive bool) Baseline {
+		c, err := New(Config{}, testSchema())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetShowSensitive(showSensitive)
+		result, err := c.Compare(p1, p2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := NewBaseline(result).Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var b Baseline
+		if err := json.Unmarshal(buf.Bytes(), &b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name          string
+		showSensitive bool
+		accepted      int
+		// number of diffs of p1 to p2 and to p3 with the baseline
+		want [2]int
+	}{
+		{"masked diffs are left out", false, 0, [2]int{1, 1}},
+		{"plain diffs with -show-sensitive", true, 1, [2]int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := baseline(tt.showSensitive)
+			if len(b.Accepted) != tt.accepted {
+				t.Fatalf("accepted: %+v", b.Accepted)
+			}
+
+			c, err := New(Config{}, testSchema())
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetShowSensitive(tt.showSensitive)
+			c.SetBaseline(b)
+
+			for i, r := range []*TfStatePlan{p2, p3} {
+				result, err := c.Compare(p1, r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(result.StateDiff.Diffs) != tt.want[i] {
+					t.Errorf("diffs to p%d: %+v", i+2, result.StateDiff.Diffs)
+				}
+			}
+		})
+	}
+}
+
+func TestIsSensitivePath(t *testing.T) {
+	// sensitive_values marks only the second element, but sets are sorted before comparison
+	sv := map[string]any{"set": []any{false, true}, "block": []any{map[string]any{"password": true}}, "tags": map[string]any{"secret": true}}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/set/0", true},
+		{"/set/1", true},
+		{"/block/0/name", true},
+		{"/tags/secret", true},
+		{"/tags/name", false},
+		{"/name", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSensitivePath(sv, tt.path); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompareThreeWaySensitive(t *testing.T) {
+	c, err := New(Config{}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := testState(sensitiveInstance("both", "p1"), sensitiveInstance("same", "p1"))
+	l := testState(sensitiveInstance("both", "p2"), sensitiveInstance("same", "p2"))
+	r := testState(sensitiveInstance("both", "p3"), sensitiveInstance("same", "p2"))
+
+	result, err := c.CompareThreeWay(base, l, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Fields) != 1 {
+		t.Fatalf("fields: %+v", result.Fields)
+	}
+	f := result.Fields[0]
+	if f.Name != "aws_instance.both" || f.Change != ChangeConflicting || f.LeftValue != sensitiveChanged || f.RightValue != sensitiveChanged {
+		t.Errorf("got %+v", f)
+	}
+}
@@ -0,0 +1,44 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs returns helper functions available in user-supplied templates
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// difference of counts, e.g. {{ delta (len .PlanDiff.Diffs) (len .StateDiff.Diffs) }}
+		"delta": func(a int, b int) string {
+			return delta(a - b)
+		},
+		"sub": func(a int, b int) int {
+			return a - b
+		},
+		"join": func(sep string, s []string) string {
+			return strings.Join(s, sep)
+		},
+		"code": func(v any) string {
+			return codeSpan(fmt.Sprint(v))
+		},
+		"codeBlock": func(v any) string {
+			return codeBlock(fmt.Sprint(v))
+		},
+	}
+}
+
+// LoadTemplate parses a text/template file to render ComparisonResult
+func LoadTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(TemplateFuncs()).ParseFiles(path)
+}
+
+// codeBlock wraps s in a fenced code block, using a longer fence than any run of backticks in s
+func codeBlock(s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + strings.TrimSuffix(s, "\n") + "\n" + fence
+}
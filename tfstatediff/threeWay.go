@@ -0,0 +1,283 @@
+package tfstatediff
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	ChangeLeftOnly    = "changed-left-only"
+	ChangeRightOnly   = "changed-right-only"
+	ChangeConflicting = "conflicting"
+)
+
+// ThreeWayResult classifies the changes from a base to left and to right
+type ThreeWayResult struct {
+	Left   *StateDiff          `json:"left"`  // base to left
+	Right  *StateDiff          `json:"right"` // base to right
+	Fields []ThreeWayFieldDiff `json:"fields"`
+}
+
+type ThreeWayFieldDiff struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"` // prefixed by the policy or document attribute and the statement if any
+	Change     string `json:"change"`
+	BaseValue  any    `json:"base_value"`  // nil if the resource is added on both sides
+	LeftValue  any    `json:"left_value"`  // nil if the resource is removed in left
+	RightValue any    `json:"right_value"` // nil if the resource is removed in right
+
+	// hashes of masked sensitive values
+	leftHash  string
+	rightHash string
+}
+
+// CompareThreeWay compares base with left and with right, planned values for plans.
+// Base is the left side of both comparisons. Fields changed the same way on both sides are omitted,
+// fields changed on one side of resources removed on the other side are conflicting,
+// and so are fields differing between resources added on both sides.
+func (c Comparer) CompareThreeWay(base *TfStatePlan, l *TfStatePlan, r *TfStatePlan) (*ThreeWayResult, error) {
+	dL, err := c.compareValues(base.plannedValues(), l.plannedValues())
+	if err != nil {
+		return nil, err
+	}
+
+	dR, err := c.compareValues(base.plannedValues(), r.plannedValues())
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := c.compareAddedResources(l.plannedValues(), r.plannedValues(), dL.RightOnly, dR.RightOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	fsL, fsR := flattenStateDiff(dL), flattenStateDiff(dR)
+
+	indexR := map[[2]string]int{}
+	for j, f := range fsR {
+		indexR[[2]string{f.Name, f.Path}] = j
+	}
+
+	removedL, removedR := map[string]bool{}, map[string]bool{}
+	for _, a := range dL.LeftOnly {
+		removedL[a] = true
+	}
+	for _, a := range dR.LeftOnly {
+		removedR[a] = true
+	}
+
+	result := ThreeWayResult{Left: dL, Right: dR, Fields: []ThreeWayFieldDiff{}}
+	foundR := map[int]bool{}
+
+	for _, f := range fsL {
+		j, ok := indexR[[2]string{f.Name, f.Path}]
+		if !ok {
+			if removedR[f.Name] {
+				f.Change, f.RightValue = ChangeConflicting, nil
+			} else {
+				f.Change, f.RightValue = ChangeLeftOnly, f.BaseValue
+			}
+			result.Fields = append(result.Fields, f)
+			continue
+		}
+
+		foundR[j] = true
+		if reflect.DeepEqual(f.LeftValue, fsR[j].RightValue) && f.leftHash == fsR[j].rightHash {
+			continue
+		}
+		f.Change, f.RightValue, f.rightHash = ChangeConflicting, fsR[j].RightValue, fsR[j].rightHash
+		result.Fields = append(result.Fields, f)
+	}
+
+	for j, f := range fsR {
+		if foundR[j] {
+			continue
+		}
+		if removedL[f.Name] {
+			f.Change, f.LeftValue = ChangeConflicting, nil
+		} else {
+			f.Change, f.LeftValue = ChangeRightOnly, f.BaseValue
+		}
+		result.Fields = append(result.Fields, f)
+	}
+
+	result.Fields = append(result.Fields, added...)
+
+	return &result, nil
+}
+
+// compareAddedResources compares the resources added on both sides, by normalized address.
+// Both sides are the right side of the comparisons with base, so address_map is not applied and the right schema is used.
+func (c Comparer) compareAddedResources(l TfValues, r TfValues, addedL []string, addedR []string) ([]ThreeWayFieldDiff, error) {
+	inR := map[string]bool{}
+	for _, a := range addedR {
+		inR[addressNormalize(a)] = true
+	}
+	both := map[string]bool{}
+	for _, a := range addedL {
+		if inR[addressNormalize(a)] {
+			both[addressNormalize(a)] = true
+		}
+	}
+	if len(both) == 0 {
+		return nil, nil
+	}
+
+	rsL, rsR := l.RootModule.resources(), r.RootModule.resources()
+	filter := func(rs []TfResource) []TfResource {
+		filtered := []TfResource{}
+		for i := range rs {
+			if both[addressNormalize(rs[i].Address)] {
+				filtered = append(filtered, rs[i])
+			}
+		}
+		return filtered
+	}
+
+	c.am = addressMapper{}
+	c.snL = c.snR
+	c.inL = newIdNormalizer(rsL, c.idSources, c.wDetail)
+	c.inR = newIdNormalizer(rsR, c.idSources, c.wDetail)
+	c.sensitive = map[string][]map[string]any{}
+	// suppressed diffs are explained in the comparisons with base
+	c.ex = nil
+
+	fmt.Fprintln(c.wDetail, "compare resources added on both sides")
+	normalizedL, errsL, err := c.normalizeResources(c.inL, c.snL, filter(rsL))
+	if err != nil {
+		return nil, err
+	}
+	normalizedR, errsR, err := c.normalizeResources(c.inR, c.snR, filter(rsR))
+	if err != nil {
+		return nil, err
+	}
+
+	// errors are already reported as resources added in each comparison with base
+	d, err := c.compareResources(normalizedL, normalizedR, errsL, errsR)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := flattenStateDiff(d)
+	for i := range fs {
+		fs[i] = ThreeWayFieldDiff{
+			Name:       fs[i].Name,
+			Path:       fs[i].Path,
+			Change:     ChangeConflicting,
+			BaseValue:  nil,
+			LeftValue:  fs[i].BaseValue,
+			RightValue: fs[i].RightValue,
+		}
+	}
+
+	return fs, nil
+}
+
+// flattenStateDiff returns the field diffs with base values and LeftValue and RightValue set to the new values
+func flattenStateDiff(d *StateDiff) []ThreeWayFieldDiff {
+	fs := []ThreeWayFieldDiff{}
+
+	walkFields(d, func(address string, path string, f FieldDiff) {
+		fs = append(fs, ThreeWayFieldDiff{
+			Name:       address,
+			Path:       path,
+			BaseValue:  f.OldValue,
+			LeftValue:  f.NewValue,
+			RightValue: f.NewValue,
+			leftHash:   f.newHash,
+			rightHash:  f.newHash,
+		})
+	})
+
+	return fs
+}
+
+// walkFields calls fn for the field diffs of resources, policies, statements and documents
+func walkFields(d *StateDiff, fn func(address string, path string, f FieldDiff)) {
+	for _, rd := range d.Diffs {
+		for _, f := range rd.Fields {
+			fn(rd.Name, fieldPath(nil, f.Path), f)
+		}
+		for _, pd := range rd.Policies {
+			for _, f := range pd.Fields {
+				fn(rd.Name, fieldPath([]string{pd.Name}, f.Path), f)
+			}
+			for _, sd := range pd.Policies {
+				for _, f := range sd.Fields {
+					fn(rd.Name, fieldPath([]string{pd.Name, sd.Name}, f.Path), f)
+				}
+			}
+		}
+		for _, dd := range rd.Documents {
+			for _, f := range dd.Fields {
+				fn(rd.Name, fieldPath([]string{dd.Name}, f.Path), f)
+			}
+		}
+	}
+	for _, od := range d.OutputDiffs {
+		for _, f := range od.Fields {
+			fn("output."+od.Name, fieldPath(nil, f.Path), f)
+		}
+	}
+}
+
+// fieldPath joins the names of the policy or document attribute and the statement to the path
+func fieldPath(prefix []string, path string) string {
+	return strings.TrimSpace(strings.Join(append(append([]string{}, prefix...), path), " "))
+}
+
+func (tr ThreeWayResult) Print(w io.Writer) {
+	counts := map[string]int{}
+
+	for _, f := range tr.Fields {
+		counts[f.Change]++
+		fmt.Fprintf(w, "%s %s (%s)\n", f.Name, f.Path, f.Change)
+		fmt.Fprintf(w, "  base:  %s\n", threeWayValue(f.BaseValue))
+		fmt.Fprintf(w, "  left:  %s\n", threeWayValue(f.LeftValue))
+		fmt.Fprintf(w, "  right: %s\n", threeWayValue(f.RightValue))
+	}
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "changed left only:   %6d\n", counts[ChangeLeftOnly])
+	fmt.Fprintf(w, "changed right only:  %6d\n", counts[ChangeRightOnly])
+	fmt.Fprintf(w, "conflicting:         %6d\n", counts[ChangeConflicting])
+	fmt.Fprintf(w, "added in left:       %6d\n", len(tr.Left.RightOnly))
+	fmt.Fprintf(w, "removed in left:     %6d\n", len(tr.Left.LeftOnly))
+	fmt.Fprintf(w, "added in right:      %6d\n", len(tr.Right.RightOnly))
+	fmt.Fprintf(w, "removed in right:    %6d\n", len(tr.Right.LeftOnly))
+
+	if errs := len(tr.Left.Errors) + len(tr.Right.Errors); errs > 0 {
+		fmt.Fprintf(w, "errors:              %6d\n", errs)
+	}
+}
+
+func (tr ThreeWayResult) PrintMarkdown(w io.Writer) {
+	fmt.Fprintln(w, "## tfstate-diff three-way")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "| resource | path | change | base | left | right |")
+	fmt.Fprintln(w, "|---|---|---|---|---|---|")
+
+	for _, f := range tr.Fields {
+		cells := []string{f.Name, f.Path, f.Change}
+		for _, v := range []any{f.BaseValue, f.LeftValue, f.RightValue} {
+			if v == nil {
+				cells = append(cells, threeWayValue(v))
+				continue
+			}
+			cells = append(cells, strings.ReplaceAll(codeSpan(fmt.Sprint(v)), "|", `\|`))
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+
+	printMarkdownDiff(w, "Base to left", tr.Left)
+	printMarkdownDiff(w, "Base to right", tr.Right)
+}
+
+func threeWayValue(v any) string {
+	if v == nil {
+		return "(no resource)"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,64 @@
+package tfstatediff
+
+import (
+	"testing"
+)
+
+func TestCompareThreeWay(t *testing.T) {
+	c, err := New(Config{}, testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := testState(
+		testInstance("left", "t3.micro"),
+		testInstance("right", "t3.micro"),
+		testInstance("both", "t3.micro"),
+		testInstance("same", "t3.micro"),
+		testInstance("removed", "t3.micro"),
+	)
+	l := testState(
+		testInstance("left", "t3.large"),
+		testInstance("right", "t3.micro"),
+		testInstance("both", "t3.large"),
+		testInstance("same", "t3.large"),
+		testInstance("removed", "t3.large"),
+		testInstance("added", "t3.large"),
+		testInstance("added_same", "t3.large"),
+	)
+	r := testState(
+		testInstance("left", "t3.micro"),
+		testInstance("right", "t3.large"),
+		testInstance("both", "t3.small"),
+		testInstance("same", "t3.large"),
+		testInstance("added", "t3.small"),
+		testInstance("added_same", "t3.large"),
+	)
+
+	result, err := c.CompareThreeWay(base, l, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]ThreeWayFieldDiff{
+		"aws_instance.left":    {Change: ChangeLeftOnly, BaseValue: `"t3.micro"`, LeftValue: `"t3.large"`, RightValue: `"t3.micro"`},
+		"aws_instance.right":   {Change: ChangeRightOnly, BaseValue: `"t3.micro"`, LeftValue: `"t3.micro"`, RightValue: `"t3.large"`},
+		"aws_instance.both":    {Change: ChangeConflicting, BaseValue: `"t3.micro"`, LeftValue: `"t3.large"`, RightValue: `"t3.small"`},
+		"aws_instance.removed": {Change: ChangeConflicting, BaseValue: `"t3.micro"`, LeftValue: `"t3.large"`, RightValue: nil},
+		"aws_instance.added":   {Change: ChangeConflicting, BaseValue: nil, LeftValue: `"t3.large"`, RightValue: `"t3.small"`},
+	}
+
+	if len(result.Fields) != len(want) {
+		t.Errorf("fields: %+v", result.Fields)
+	}
+	for _, f := range result.Fields {
+		w, ok := want[f.Name]
+		if !ok {
+			t.Errorf("unexpected field: %+v", f)
+			continue
+		}
+		if f.Path != "/instance_type" || f.Change != w.Change || f.BaseValue != w.BaseValue || f.LeftValue != w.LeftValue || f.RightValue != w.RightValue {
+			t.Errorf("got %+v, want %+v", f, w)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package tfstatediff
+
+import "fmt"
+
+// UnusedRules returns the configured rules which have never matched in the comparisons so far
+func (c *Comparer) UnusedRules() []string {
+	unused := []string{}
+
+	for i := range c.ignorePattern {
+		if *c.ignorePattern[i].hits == 0 {
+			p := c.config.IgnorePattern[i]
+			unused = append(unused, fmt.Sprintf("ignore_pattern[%d] (address: %q, path: %q)", i, p.Address, p.Path))
+		}
+	}
+
+	for i := range c.ignoreDiff {
+		if *c.ignoreDiff[i].hits == 0 {
+			unused = append(unused, fmt.Sprintf("%s (%s)", c.ignoreDiff[i].name, c.ignoreDiff[i]))
+		}
+	}
+
+	for i := range c.am.rules {
+		if *c.am.rules[i].hits == 0 {
+			unused = append(unused, fmt.Sprintf("address_map[%d] (%s)", i, c.am.rules[i]))
+		}
+	}
+
+	for i := range c.baseline {
+		if *c.baseline[i].hits == 0 {
+			unused = append(unused, fmt.Sprintf("baseline[%d] (address: %q, path: %q)", i, c.baseline[i].Address, c.baseline[i].Path))
+		}
+	}
+
+	return unused
+}
@@ -0,0 +1,321 @@
+package tfstatediff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Diagnostic struct {
+	Line     int
+	Severity string // error or warning
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d: %s: %s", d.Line, d.Severity, d.Message)
+}
+
+type configValidator struct {
+	ps          *TfProvidersSchema
+	diagnostics []Diagnostic
+}
+
+// ValidateConfig checks a configuration file strictly, and the resource types and attributes referred in it
+// against the providers schema if ps is not nil
+func ValidateConfig(path string, ps *TfProvidersSchema) ([]Diagnostic, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v := configValidator{ps: ps}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		v.addYamlError(err)
+		return v.diagnostics, nil
+	}
+	if len(root.Content) == 0 {
+		return v.diagnostics, nil
+	}
+
+	var c Config
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	if err := d.Decode(&c); err != nil {
+		v.addYamlError(err)
+	}
+
+	doc := root.Content[0]
+	v.validateIgnorePatterns(c.IgnorePattern, sequence(doc, "ignore_pattern"))
+	v.validatePlaceholders(c.Placeholders, mapping(doc, "placeholders"))
+	v.validateIgnoreDiffs(c.IgnoreDiff, c.Placeholders, sequence(doc, "ignore_diff"))
+	v.validateAddressMaps(c.AddressMap, sequence(doc, "address_map"))
+	v.validateIdSources(c.IdSources, sequence(doc, "id_sources"))
+	v.validateDocuments(c.Documents, sequence(doc, "documents"))
+
+	sort.SliceStable(v.diagnostics, func(i int, j int) bool {
+		return v.diagnostics[i].Line < v.diagnostics[j].Line
+	})
+
+	return v.diagnostics, nil
+}
+
+func (v *configValidator) add(line int, severity string, format string, a ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{Line: line, Severity: severity, Message: fmt.Sprintf(format, a...)})
+}
+
+var yamlErrorLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func (v *configValidator) addYamlError(err error) {
+	messages := []string{err.Error()}
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		messages = te.Errors
+	}
+
+	for _, m := range messages {
+		line := 0
+		if sm := yamlErrorLinePattern.FindStringSubmatch(m); sm != nil {
+			fmt.Sscan(sm[1], &line)
+			m = sm[2]
+		}
+		v.add(line, "error", "%s", m)
+	}
+}
+
+// mapping returns the value node of the key in a mapping node
+func mapping(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func sequence(n *yaml.Node, key string) []*yaml.Node {
+	s := mapping(n, key)
+	if s == nil || s.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return s.Content
+}
+
+// line returns the line of the field in the i-th item, or of the item itself
+func line(items []*yaml.Node, i int, field string) int {
+	if i >= len(items) {
+		return 0
+	}
+	if f := mapping(items[i], field); f != nil {
+		return f.Line
+	}
+	return items[i].Line
+}
+
+func (v *configValidator) compile(line int, name string, pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		v.add(line, "error", "%s: %s", name, err)
+		return nil
+	}
+	return re
+}
+
+func (v *configValidator) validateIgnorePatterns(c []ConfigIgnorePattern, items []*yaml.Node) {
+	for i := range c {
+		if c[i].Address == "" && c[i].Path == "" {
+			v.add(line(items, i, ""), "warning", "ignore_pattern[%d]: ignores everything without address and path", i)
+		}
+		if c[i].Address != "" {
+			if re := v.compile(line(items, i, "address"), fmt.Sprintf("ignore_pattern[%d].address", i), c[i].Address); re != nil {
+				v.checkTypeInPattern(line(items, i, "address"), fmt.Sprintf("ignore_pattern[%d].address", i), c[i].Address)
+			}
+		}
+		if c[i].Path != "" {
+			v.compile(line(items, i, "path"), fmt.Sprintf("ignore_pattern[%d].path", i), c[i].Path)
+		}
+	}
+}
+
+func (v *configValidator) validatePlaceholders(c map[string]ConfigPlaceholder, n *yaml.Node) {
+	for name, p := range c {
+		if p.Left == "" && p.Right == "" {
+			l := 0
+			if m := mapping(n, name); m != nil {
+				l = m.Line
+			}
+			v.add(l, "error", "placeholders.%s: left or right is required", name)
+		}
+	}
+}
+
+func (v *configValidator) validateIgnoreDiffs(c []ConfigIgnoreDiff, placeholders map[string]ConfigPlaceholder, items []*yaml.Node) {
+	for i := range c {
+		for _, f := range []struct {
+			name  string
+			value string
+		}{{"left", c[i].Left}, {"right", c[i].Right}} {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(f.value, -1) {
+				if _, ok := placeholders[m[1]]; !ok {
+					v.add(line(items, i, f.name), "error", "ignore_diff[%d].%s: unknown placeholder: %s", i, f.name, m[0])
+				}
+			}
+		}
+		if c[i].Left == "" && c[i].Right == "" {
+			v.add(line(items, i, ""), "error", "ignore_diff[%d]: left or right is required", i)
+		}
+		if c[i].Regexp {
+			// unknown placeholders are reported above
+			left, err := expandPlaceholders(c[i].Left, placeholders, leftOf, regexp.QuoteMeta)
+			if err == nil {
+				if _, _, err := compileIgnoreDiff(left); err != nil {
+					v.add(line(items, i, "left"), "error", "ignore_diff[%d].left: %s", i, err)
+				}
+			}
+		}
+	}
+}
+
+func (v *configValidator) validateAddressMaps(c []ConfigAddressMap, items []*yaml.Node) {
+	for i := range c {
+		name := fmt.Sprintf("address_map[%d]", i)
+		if c[i].Pattern != "" {
+			if c[i].Left != "" || c[i].Right != "" {
+				v.add(line(items, i, "pattern"), "error", "%s: pattern cannot be used with left/right", name)
+			}
+			v.compile(line(items, i, "pattern"), name+".pattern", c[i].Pattern)
+			continue
+		}
+		if c[i].Left == "" || c[i].Right == "" {
+			v.add(line(items, i, ""), "error", "%s: either pattern or both left and right are required", name)
+			continue
+		}
+		v.checkAddress(line(items, i, "left"), name+".left", c[i].Left)
+		v.checkAddress(line(items, i, "right"), name+".right", c[i].Right)
+	}
+}
+
+func (v *configValidator) validateIdSources(c []ConfigIdSource, items []*yaml.Node) {
+	for i := range c {
+		name := fmt.Sprintf("id_sources[%d]", i)
+		if (c[i].Type == "") == (c[i].TypePattern == "") {
+			v.add(line(items, i, ""), "error", "%s: either type or type_pattern is required", name)
+			continue
+		}
+		if len(c[i].Attributes) == 0 {
+			v.add(line(items, i, ""), "error", "%s: attributes are required", name)
+		}
+
+		schemas := []TfSchema{}
+		if c[i].Type != "" {
+			if s, ok := v.findType(c[i].Type); ok {
+				schemas = append(schemas, s)
+			} else if v.ps != nil {
+				v.add(line(items, i, "type"), "error", "%s.type: resource type not found in schema: %s", name, c[i].Type)
+			}
+		} else {
+			re := v.compile(line(items, i, "type_pattern"), name+".type_pattern", "^(?:"+c[i].TypePattern+")$")
+			if re == nil || v.ps == nil {
+				continue
+			}
+			for t, s := range v.types() {
+				if re.MatchString(t) {
+					schemas = append(schemas, s)
+				}
+			}
+			if len(schemas) == 0 {
+				v.add(line(items, i, "type_pattern"), "warning", "%s.type_pattern: matches no resource type in schema", name)
+			}
+		}
+
+		for _, attr := range c[i].Attributes {
+			found := len(schemas) == 0
+			for _, s := range schemas {
+				if _, ok := s.Block.Attributes[attr]; ok {
+					found = true
+				}
+			}
+			if !found {
+				v.add(line(items, i, "attributes"), "error", "%s.attributes: attribute not found in schema: %s", name, attr)
+			}
+		}
+	}
+}
+
+func (v *configValidator) validateDocuments(c []ConfigDocument, items []*yaml.Node) {
+	for i := range c {
+		name := fmt.Sprintf("documents[%d]", i)
+		v.compile(line(items, i, "path"), name+".path", c[i].Path)
+		if _, ok := documentDecoders[c[i].Format]; !ok {
+			v.add(line(items, i, "format"), "error", "%s.format: unknown format: %s", name, c[i].Format)
+		}
+	}
+}
+
+// types returns resource and data source schemas of all providers by type
+func (v *configValidator) types() map[string]TfSchema {
+	types := map[string]TfSchema{}
+	if v.ps == nil {
+		return types
+	}
+	for _, p := range v.ps.ProviderSchema {
+		for t, s := range p.DataSourceSchemas {
+			types[t] = s
+		}
+		for t, s := range p.ResourceSchemas {
+			types[t] = s
+		}
+	}
+	return types
+}
+
+func (v *configValidator) findType(t string) (TfSchema, bool) {
+	s, ok := v.types()[t]
+	return s, ok
+}
+
+var resourceTypeInAddressPattern = regexp.MustCompile(`^(?:module\.[^.]+\.)*(?:data\.)?([a-z0-9_]+)\.`)
+
+// checkAddress checks the resource type of an address, e.g. module.a.aws_vpc.main
+func (v *configValidator) checkAddress(line int, name string, address string) {
+	if v.ps == nil {
+		return
+	}
+	m := resourceTypeInAddressPattern.FindStringSubmatch(address)
+	if m == nil {
+		v.add(line, "error", "%s: invalid resource address: %s", name, address)
+		return
+	}
+	if _, ok := v.findType(m[1]); !ok {
+		v.add(line, "error", "%s: resource type not found in schema: %s", name, m[1])
+	}
+}
+
+// checkTypeInPattern checks a pattern looking like a plain resource type or address
+func (v *configValidator) checkTypeInPattern(line int, name string, pattern string) {
+	if v.ps == nil {
+		return
+	}
+	p := strings.TrimSuffix(strings.TrimPrefix(pattern, "^"), "$")
+	if strings.ContainsAny(p, `\()[]{}*+?|`) {
+		return
+	}
+	t := p
+	if m := resourceTypeInAddressPattern.FindStringSubmatch(p + "."); m != nil {
+		t = m[1]
+	}
+	if _, ok := v.findType(t); !ok {
+		v.add(line, "warning", "%s: resource type not found in schema: %s", name, t)
+	}
+}
@@ -0,0 +1,139 @@
+package tfstatediff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func validate(t *testing.T, config string, ps *TfProvidersSchema) []string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := ValidateConfig(path, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, d := range ds {
+		got = append(got, d.String())
+	}
+	return got
+}
+
+func TestValidateConfig(t *testing.T) {
+	schema := testSchema()
+
+	tests := []struct {
+		name   string
+		config string
+		ps     *TfProvidersSchema
+		want   []string
+	}{
+		{"empty", "", &schema, []string{}},
+		{
+			name: "valid",
+			config: `ignore_pattern:
+  - address: "aws_instance"
+    path: "/tags"
+placeholders:
+  env:
+    left: stg
+    right: prod
+ignore_diff:
+  - left: "{env}-(\\d+)"
+    right: "{env}-$1"
+    regexp: true
+id_sources:
+  - type: aws_instance
+    attributes: [arn]
+`,
+			ps:   &schema,
+			want: []string{},
+		},
+		{
+			name: "syntax error",
+			config: `ignore_pattern:
+  - path: [
+`,
+			want: []string{"2: error: did not find expected node content"},
+		},
+		{
+			name: "unknown field",
+			config: `ignore_pattern:
+  - pth: "/tags"
+`,
+			want: []string{"2: error: field pth not found in type tfstatediff.ConfigIgnorePattern", "2: warning: ignore_pattern[0]: ignores everything without address and path"},
+		},
+		{
+			name: "ignore_diff",
+			config: `placeholders:
+  env: {}
+ignore_diff:
+  - left: "{account}"
+    right: "prod"
+  - left: "a)|(?:b"
+    right: "c"
+    regexp: true
+  - regexp: true
+`,
+			want: []string{
+				"2: error: placeholders.env: left or right is required",
+				"4: error: ignore_diff[0].left: unknown placeholder: {account}",
+				"6: error: ignore_diff[1].left: error parsing regexp: unexpected ): `a)|(?:b`",
+				"9: error: ignore_diff[2]: left or right is required",
+			},
+		},
+		{
+			name: "schema",
+			config: `ignore_pattern:
+  - address: "aws_instanse"
+address_map:
+  - left: module.a.aws_vpc.a
+    right: module.b.aws_instance.a
+  - pattern: "("
+id_sources:
+  - type: aws_instance
+    attributes: [subnet_ids]
+  - type_pattern: "google_.+"
+    attributes: [id]
+documents:
+  - path: "/user_data$"
+    format: xml
+`,
+			ps: &schema,
+			want: []string{
+				"2: warning: ignore_pattern[0].address: resource type not found in schema: aws_instanse",
+				"4: error: address_map[0].left: resource type not found in schema: aws_vpc",
+				"6: error: address_map[1].pattern: error parsing regexp: missing closing ): `(`",
+				"9: error: id_sources[0].attributes: attribute not found in schema: subnet_ids",
+				"10: warning: id_sources[1].type_pattern: matches no resource type in schema",
+				"14: error: documents[0].format: unknown format: xml",
+			},
+		},
+		{
+			name: "without schema",
+			config: `address_map:
+  - left: module.a.aws_vpc.a
+    right: module.b.aws_vpc.a
+id_sources:
+  - type: aws_vpc
+    attributes: [id]
+`,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validate(t, tt.config, tt.ps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}